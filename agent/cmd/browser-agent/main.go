@@ -2,16 +2,86 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/volant-plugins/browser/internal/runtime/app"
 )
 
 func main() {
-	ctx := context.Background()
-	if err := app.Run(ctx); err != nil {
+	if len(os.Args) > 1 && os.Args[1] == "manifest" {
+		os.Exit(runManifest(os.Args[2:]))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	if err := app.Run(ctx); err != nil && !errors.Is(err, context.Canceled) {
 		log.Printf("exit due to error: %v", err)
 		os.Exit(1)
 	}
 }
+
+// runManifest implements `browser-agent manifest`: it prints a manifest
+// generated from the action registry, or with --check reports where an
+// existing manifest has drifted from it.
+func runManifest(args []string) int {
+	flags := flag.NewFlagSet("manifest", flag.ContinueOnError)
+	base := flags.String("base", "", "existing manifest whose non-action fields are carried over")
+	check := flags.String("check", "", "manifest to verify against the registry; exits 1 on mismatch")
+	out := flags.String("out", "", "write the manifest to this file instead of stdout")
+	version := flags.String("version", "", "version to record (or require with --check); by default the base manifest's is kept")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *check != "" {
+		data, err := os.ReadFile(*check)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "manifest: %v\n", err)
+			return 2
+		}
+		diffs, err := app.CheckManifest(data, *version)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "manifest: %s: %v\n", *check, err)
+			return 2
+		}
+		if len(diffs) == 0 {
+			return 0
+		}
+		fmt.Fprintf(os.Stderr, "%s is out of date with the action registry:\n", *check)
+		for _, diff := range diffs {
+			fmt.Fprintf(os.Stderr, "  %s\n", diff)
+		}
+		return 1
+	}
+
+	var baseData []byte
+	if *base != "" {
+		data, err := os.ReadFile(*base)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "manifest: %v\n", err)
+			return 2
+		}
+		baseData = data
+	}
+	manifest, err := app.GenerateManifest(baseData, *version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "manifest: %v\n", err)
+		return 1
+	}
+	if *out == "" {
+		os.Stdout.Write(manifest)
+		return 0
+	}
+	if err := os.WriteFile(*out, manifest, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "manifest: %v\n", err)
+		return 1
+	}
+	return 0
+}
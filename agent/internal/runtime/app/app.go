@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"time"
@@ -27,6 +28,22 @@ const (
 	defaultRemotePortKey  = "volant_AGENT_REMOTE_DEBUGGING_PORT"
 	defaultUserDataDirKey = "volant_AGENT_USER_DATA_DIR"
 	defaultExecPathKey    = "volant_AGENT_EXEC_PATH"
+	sessionIdleEnvKey     = "volant_AGENT_SESSION_IDLE_TIMEOUT"
+	maxConcurrencyEnvKey  = "volant_AGENT_MAX_CONCURRENCY"
+	queueSizeEnvKey       = "volant_AGENT_QUEUE_SIZE"
+	shutdownGraceEnvKey   = "volant_AGENT_SHUTDOWN_GRACE"
+	profileSnapshotEnvKey = "volant_AGENT_PROFILE_SNAPSHOT_PATH"
+	blockTypesEnvKey      = "volant_AGENT_BLOCK_RESOURCE_TYPES"
+	blockPatternsEnvKey   = "volant_AGENT_BLOCK_URL_PATTERNS"
+	proxyServerEnvKey     = "volant_AGENT_PROXY_SERVER"
+	proxyBypassEnvKey     = "volant_AGENT_PROXY_BYPASS"
+	ignoreCertsEnvKey     = "volant_AGENT_IGNORE_CERT_ERRORS"
+	caBundleEnvKey        = "volant_AGENT_CA_BUNDLE"
+	maxUploadEnvKey       = "volant_AGENT_MAX_UPLOAD_BYTES"
+
+	defaultShutdownGrace = 10 * time.Second
+
+	readinessTimeout = 2 * time.Second
 )
 
 type Config struct {
@@ -36,12 +53,20 @@ type Config struct {
 	UserDataDir         string
 	ExecPath            string
 	DefaultTimeout      time.Duration
+	SessionIdleTimeout  time.Duration
+	MaxConcurrency      int
+	QueueSize           int
+	ShutdownGrace       time.Duration
+	ProfileSnapshotPath string
+	BlockPolicy         browser.BlockPolicy
+	Proxy               browser.ProxyConfig
+	Certificates        browser.CertificatePolicy
+	MaxUploadBytes      int64
 }
 
 type App struct {
 	cfg     Config
 	runtime *browser.Runtime
-	timeout time.Duration
 	log     *log.Logger
 	started time.Time
 }
@@ -56,26 +81,35 @@ func Run(ctx context.Context) error {
 	}
 
 	options := browser.Options{
-		DefaultTimeout: cfg.DefaultTimeout,
-		RemoteAddr:     cfg.RemoteDebuggingAddr,
-		RemotePort:     cfg.RemoteDebuggingPort,
-		UserDataDir:    cfg.UserDataDir,
-		ExecPath:       cfg.ExecPath,
+		DefaultTimeout:     cfg.DefaultTimeout,
+		RemoteAddr:         cfg.RemoteDebuggingAddr,
+		RemotePort:         cfg.RemoteDebuggingPort,
+		UserDataDir:        cfg.UserDataDir,
+		ExecPath:           cfg.ExecPath,
+		SessionIdleTimeout: cfg.SessionIdleTimeout,
+		MaxConcurrency:     cfg.MaxConcurrency,
+		QueueSize:          cfg.QueueSize,
+		ProfileSnapshot:    cfg.ProfileSnapshotPath,
+		BlockPolicy:        cfg.BlockPolicy,
+		Proxy:              cfg.Proxy,
+		Certificates:       cfg.Certificates,
+		RequestTimeout:     cfg.DefaultTimeout + 30*time.Second,
+		MaxUploadBytes:     cfg.MaxUploadBytes,
 	}
 	if manifest != nil {
 		options.Manifest = manifest
 	}
 
-	runtimeInstance, err := browser.New(ctx, options)
+	// Chrome must outlive the signal context so in-flight actions can drain
+	// after a stop signal; the runtime is torn down explicitly in run.
+	runtimeInstance, err := browser.New(context.WithoutCancel(ctx), options)
 	if err != nil {
 		return err
 	}
-	defer runtimeInstance.Shutdown(context.Background())
 
 	app := &App{
 		cfg:     cfg,
 		runtime: runtimeInstance,
-		timeout: cfg.DefaultTimeout,
 		log:     logger,
 		started: time.Now().UTC(),
 	}
@@ -87,15 +121,13 @@ func (a *App) run(ctx context.Context) error {
 	router.Use(middleware.RequestID)
 	router.Use(middleware.RealIP)
 	router.Use(middleware.Recoverer)
-	router.Use(middleware.Timeout(a.timeout + 30*time.Second))
-
-	router.Get("/healthz", a.handleHealth)
 
-	router.Route("/v1", func(r chi.Router) {
-		r.Get("/devtools", a.handleDevTools)
-		r.Get("/logs/stream", a.handleLogs)
-		a.runtime.MountRoutes(r)
-	})
+	a.registerActions()
+	if err := a.runtime.MountRoutes(router); err != nil {
+		// Nothing has run yet, so skip the drain and profile snapshot.
+		a.runtime.BrowserInstance().Close()
+		return err
+	}
 
 	server := &http.Server{
 		Addr:         a.cfg.ListenAddr,
@@ -119,17 +151,38 @@ func (a *App) run(ctx context.Context) error {
 
 	select {
 	case <-ctx.Done():
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		a.log.Printf("shutdown requested, draining for up to %s", a.cfg.ShutdownGrace)
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), a.cfg.ShutdownGrace)
 		defer cancel()
 		if err := server.Shutdown(shutdownCtx); err != nil {
 			a.log.Printf("shutdown error: %v", err)
 		}
+		a.shutdownRuntime(shutdownCtx)
 		return ctx.Err()
 	case err := <-errCh:
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), a.cfg.ShutdownGrace)
+		defer cancel()
+		a.shutdownRuntime(shutdownCtx)
 		return err
 	}
 }
 
+// registerActions adds the agent-level endpoints to the runtime's action
+// registry so the manifest can route and time them like browser actions.
+func (a *App) registerActions() {
+	for _, action := range a.appActions() {
+		a.runtime.RegisterAction(action)
+	}
+}
+
+func (a *App) shutdownRuntime(ctx context.Context) {
+	if err := a.runtime.Shutdown(ctx); err != nil {
+		a.log.Printf("runtime shutdown error: %v", err)
+		return
+	}
+	a.log.Printf("runtime shut down cleanly")
+}
+
 func loadConfig() Config {
 	remoteAddr := envOrDefault(defaultRemoteAddrKey, browser.DefaultRemoteAddr)
 	remotePort := envIntOrDefault(defaultRemotePortKey, browser.DefaultRemotePort)
@@ -142,6 +195,24 @@ func loadConfig() Config {
 		UserDataDir:         os.Getenv(defaultUserDataDirKey),
 		ExecPath:            os.Getenv(defaultExecPathKey),
 		DefaultTimeout:      defaultTimeout,
+		SessionIdleTimeout:  parseDurationEnv(sessionIdleEnvKey, browser.DefaultSessionIdleTimeout),
+		MaxConcurrency:      envIntOrDefault(maxConcurrencyEnvKey, browser.DefaultMaxConcurrency),
+		QueueSize:           envIntOrDefault(queueSizeEnvKey, browser.DefaultQueueSize),
+		ShutdownGrace:       parseDurationEnv(shutdownGraceEnvKey, defaultShutdownGrace),
+		ProfileSnapshotPath: strings.TrimSpace(os.Getenv(profileSnapshotEnvKey)),
+		BlockPolicy: browser.BlockPolicy{
+			ResourceTypes: envList(blockTypesEnvKey),
+			URLPatterns:   envList(blockPatternsEnvKey),
+		},
+		Proxy: browser.ProxyConfig{
+			Server: strings.TrimSpace(os.Getenv(proxyServerEnvKey)),
+			Bypass: envList(proxyBypassEnvKey),
+		},
+		Certificates: browser.CertificatePolicy{
+			IgnoreErrors: envBool(ignoreCertsEnvKey),
+			CABundle:     strings.TrimSpace(os.Getenv(caBundleEnvKey)),
+		},
+		MaxUploadBytes: int64(envIntOrDefault(maxUploadEnvKey, browser.DefaultMaxUploadBytes)),
 	}
 }
 
@@ -152,6 +223,23 @@ func envOrDefault(key, fallback string) string {
 	return fallback
 }
 
+// envList splits a comma-separated variable, dropping empty items.
+func envList(key string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// envBool reports whether a variable is set to a true value.
+func envBool(key string) bool {
+	enabled, _ := strconv.ParseBool(strings.TrimSpace(os.Getenv(key)))
+	return enabled
+}
+
 func envIntOrDefault(key string, fallback int) int {
 	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
 		if parsed, err := strconv.Atoi(value); err == nil && parsed > 0 {
@@ -185,14 +273,97 @@ func resolveManifest() (*pluginspec.Manifest, error) {
 	return &manifest, nil
 }
 
+// handleHealth backs the manifest's health check, so it stays 200 while the
+// supervisor recovers Chrome; the browser state is reported in the body and
+// /readyz is the endpoint that fails until Chrome answers again.
 func (a *App) handleHealth(w http.ResponseWriter, r *http.Request) {
+	browserStatus := a.runtime.Status()
+	status := "ok"
+	if browserStatus.State != "running" {
+		status = "degraded"
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"status":  status,
+		"state":   browserStatus.State,
+		"uptime":  time.Since(a.started).Round(time.Second).String(),
+		"version": buildVersion(),
+		"browser": browserStatus,
+	})
+}
+
+// handleLive reports that the agent process is serving requests. It does not
+// touch Chrome, so a wedged browser never gets the agent itself restarted.
+func (a *App) handleLive(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]any{
 		"status":  "ok",
 		"uptime":  time.Since(a.started).Round(time.Second).String(),
-		"version": "v0.1.0",
+		"version": buildVersion(),
+	})
+}
+
+// handleReady performs a CDP round trip with a short deadline and only
+// reports ready when Chrome answers.
+func (a *App) handleReady(w http.ResponseWriter, r *http.Request) {
+	browserStatus := a.runtime.Status()
+	probe, err := a.runtime.Probe(readinessTimeout)
+	if err != nil {
+		respondJSON(w, http.StatusServiceUnavailable, map[string]any{
+			"status":  "unavailable",
+			"error":   err.Error(),
+			"version": buildVersion(),
+			"browser": browserStatus,
+		})
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]any{
+		"status":  "ready",
+		"uptime":  time.Since(a.started).Round(time.Second).String(),
+		"version": buildVersion(),
+		"browser": browserStatus,
+		"probe":   probe,
 	})
 }
 
+// Version is the agent version stamped at link time with
+// -ldflags "-X github.com/volant-plugins/browser/internal/runtime/app.Version=...".
+var Version string
+
+// buildVersion reports the stamped Version, or derives one from the embedded
+// build info, falling back to the VCS revision for untagged builds. It is
+// only used for runtime reporting; manifests take their version explicitly.
+func buildVersion() string {
+	if Version != "" {
+		return Version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if version := info.Main.Version; version != "" && version != "(devel)" {
+		return version
+	}
+	var revision string
+	var modified bool
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if revision == "" {
+		return "devel"
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if modified {
+		revision += "-dirty"
+	}
+	return "devel+" + revision
+}
+
 func (a *App) handleDevTools(w http.ResponseWriter, r *http.Request) {
 	info, ok := a.runtime.DevToolsInfo()
 	if !ok {
@@ -0,0 +1,247 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+
+	pluginspec "github.com/ccheshirecat/volant/pkg/pluginspec"
+	"github.com/volant-plugins/browser/internal/runtime/browser"
+)
+
+const (
+	manifestSchemaVersion = "1.0"
+	defaultCPUCores       = 2
+	defaultMemoryMB       = 2048
+
+	// unversioned is recorded when a manifest is generated from scratch
+	// without an explicit version.
+	unversioned = "devel"
+)
+
+// appActions lists the endpoints served by the agent itself rather than the
+// browser runtime.
+func (a *App) appActions() []browser.Action {
+	return []browser.Action{
+		{Name: "health", Description: "Health probe", Method: http.MethodGet, Path: "/healthz", TimeoutMs: 5000, Handler: a.handleHealth},
+		{Name: "live", Description: "Agent liveness", Method: http.MethodGet, Path: "/livez", TimeoutMs: 5000, Handler: a.handleLive},
+		{Name: "ready", Description: "Browser readiness over CDP", Method: http.MethodGet, Path: "/readyz", TimeoutMs: 5000, Handler: a.handleReady},
+		{Name: "devtools", Description: "Fetch DevTools websocket info", Method: http.MethodGet, Path: "/v1/devtools", TimeoutMs: 15000, Handler: a.handleDevTools},
+		{Name: "logs", Description: "Stream agent logs", Method: http.MethodGet, Path: "/v1/logs/stream", TimeoutMs: 60000, Handler: a.handleLogs, Stream: true},
+	}
+}
+
+// GenerateManifest builds the plugin manifest from the in-code action
+// registry. When base is a manifest document, the fields the registry does
+// not own (image, artifacts, labels, ...) are carried over in their original
+// order and only actions, resources and health_check are replaced. Actions
+// keep the method, path and timeout base declares for them, since those are
+// the published routes the agent mounts; only new actions take the registry
+// defaults. The version is only replaced when one is given, so the output
+// never depends on how the generating binary was built.
+func GenerateManifest(base []byte, version string) ([]byte, error) {
+	if len(bytes.TrimSpace(base)) == 0 {
+		data, err := json.MarshalIndent(registryManifest(version, nil), "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("manifest: encode: %w", err)
+		}
+		return append(data, '\n'), nil
+	}
+
+	keys, fields, err := decodeOrderedObject(base)
+	if err != nil {
+		return nil, err
+	}
+	var declared map[string]pluginspec.Action
+	if raw, ok := fields["actions"]; ok {
+		if err := json.Unmarshal(raw, &declared); err != nil {
+			return nil, fmt.Errorf("manifest: decode base actions: %w", err)
+		}
+	}
+	generated := registryManifest(version, declared)
+	owned := map[string]any{
+		"actions":      generated.Actions,
+		"resources":    generated.Resources,
+		"health_check": generated.HealthCheck,
+	}
+	ownedKeys := []string{"resources", "actions", "health_check"}
+	if version != "" {
+		owned["version"] = generated.Version
+		ownedKeys = append(ownedKeys, "version")
+	}
+	for _, key := range ownedKeys {
+		raw, err := json.Marshal(owned[key])
+		if err != nil {
+			return nil, fmt.Errorf("manifest: encode %s: %w", key, err)
+		}
+		if _, ok := fields[key]; !ok {
+			keys = append(keys, key)
+		}
+		fields[key] = raw
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(fields[key])
+	}
+	buf.WriteByte('}')
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", "  "); err != nil {
+		return nil, fmt.Errorf("manifest: encode: %w", err)
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// CheckManifest compares the registry-owned parts of an existing manifest with
+// what GenerateManifest would produce and describes each difference. Declared
+// routes are the manifest's own, so only missing ones are reported. The
+// version is only compared when one is given.
+func CheckManifest(existing []byte, version string) ([]string, error) {
+	var current pluginspec.Manifest
+	if err := json.Unmarshal(existing, &current); err != nil {
+		return nil, fmt.Errorf("manifest: decode: %w", err)
+	}
+	generated := registryManifest(version, current.Actions)
+
+	var diffs []string
+	if version != "" && current.Version != version {
+		diffs = append(diffs, fmt.Sprintf("version: manifest has %s, want %s", current.Version, version))
+	}
+	if !reflect.DeepEqual(current.Resources, generated.Resources) {
+		diffs = append(diffs, fmt.Sprintf("resources: manifest has %+v, registry has %+v", current.Resources, generated.Resources))
+	}
+	if !reflect.DeepEqual(current.HealthCheck, generated.HealthCheck) {
+		diffs = append(diffs, fmt.Sprintf("health_check: manifest has %+v, registry has %+v", current.HealthCheck, generated.HealthCheck))
+	}
+
+	names := make(map[string]struct{})
+	for name := range current.Actions {
+		names[name] = struct{}{}
+	}
+	for name := range generated.Actions {
+		names[name] = struct{}{}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		have, inManifest := current.Actions[name]
+		want, inRegistry := generated.Actions[name]
+		switch {
+		case !inManifest:
+			diffs = append(diffs, fmt.Sprintf("actions.%s: missing from manifest", name))
+		case !inRegistry:
+			diffs = append(diffs, fmt.Sprintf("actions.%s: not in registry", name))
+		default:
+			diffs = append(diffs, actionDiffs(name, have, want)...)
+		}
+	}
+	return diffs, nil
+}
+
+func actionDiffs(name string, have, want pluginspec.Action) []string {
+	var diffs []string
+	field := func(key string, have, want any) {
+		if !reflect.DeepEqual(have, want) {
+			diffs = append(diffs, fmt.Sprintf("actions.%s.%s: manifest has %v, registry has %v", name, key, have, want))
+		}
+	}
+	field("description", fmt.Sprintf("%q", have.Description), fmt.Sprintf("%q", want.Description))
+	field("method", have.Method, want.Method)
+	field("path", have.Path, want.Path)
+	field("timeout_ms", have.TimeoutMs, want.TimeoutMs)
+	return diffs
+}
+
+// registryManifest describes the registry's actions, taking the method, path
+// and timeout from declared where it sets them.
+func registryManifest(version string, declared map[string]pluginspec.Action) pluginspec.Manifest {
+	actions := append(browser.DefaultActions(), (&App{}).appActions()...)
+	if version == "" {
+		version = unversioned
+	}
+
+	manifest := pluginspec.Manifest{
+		SchemaVersion: manifestSchemaVersion,
+		Name:          browser.Name,
+		Version:       version,
+		Runtime:       browser.Name,
+		Enabled:       true,
+		Resources: pluginspec.ResourceSpec{
+			CPUCores: defaultCPUCores,
+			MemoryMB: defaultMemoryMB,
+		},
+		Actions: make(map[string]pluginspec.Action, len(actions)),
+		Labels: map[string]string{
+			"volant.plugin":  browser.Name,
+			"volant.runtime": browser.Name,
+		},
+	}
+	for _, action := range actions {
+		entry := pluginspec.Action{
+			Description: action.Description,
+			Method:      action.Method,
+			Path:        action.Path,
+			TimeoutMs:   action.TimeoutMs,
+		}
+		if route, ok := declared[action.Name]; ok {
+			if route.Method != "" {
+				entry.Method = route.Method
+			}
+			if route.Path != "" {
+				entry.Path = route.Path
+			}
+			if route.TimeoutMs > 0 {
+				entry.TimeoutMs = route.TimeoutMs
+			}
+		}
+		manifest.Actions[action.Name] = entry
+		if action.Name == "health" {
+			manifest.HealthCheck = pluginspec.HealthCheck{
+				Endpoint:  entry.Path,
+				TimeoutMs: entry.TimeoutMs,
+			}
+		}
+	}
+	return manifest
+}
+
+func decodeOrderedObject(data []byte) ([]string, map[string]json.RawMessage, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, nil, fmt.Errorf("manifest: base is not a JSON object")
+	}
+	var keys []string
+	fields := make(map[string]json.RawMessage)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, fmt.Errorf("manifest: decode base: %w", err)
+		}
+		key, _ := tok.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, nil, fmt.Errorf("manifest: decode base: %w", err)
+		}
+		if _, seen := fields[key]; !seen {
+			keys = append(keys, key)
+		}
+		fields[key] = raw
+	}
+	return keys, fields, nil
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	pluginspec "github.com/ccheshirecat/volant/pkg/pluginspec"
+)
+
+func TestGenerateManifestVersion(t *testing.T) {
+	base := []byte(`{"name": "browser", "version": "0.1.0", "image": "example/browser:0.1.0"}`)
+	tests := []struct {
+		name    string
+		base    []byte
+		version string
+		want    string
+	}{
+		{"scratch without version", nil, "", unversioned},
+		{"scratch with version", nil, "1.2.3", "1.2.3"},
+		{"base keeps its version", base, "", "0.1.0"},
+		{"explicit version replaces base", base, "1.2.3", "1.2.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, err := GenerateManifest(tt.base, tt.version)
+			if err != nil {
+				t.Fatalf("GenerateManifest: %v", err)
+			}
+			second, err := GenerateManifest(tt.base, tt.version)
+			if err != nil {
+				t.Fatalf("GenerateManifest: %v", err)
+			}
+			if !bytes.Equal(first, second) {
+				t.Fatal("GenerateManifest is not deterministic")
+			}
+			var decoded struct {
+				Version string `json:"version"`
+			}
+			if err := json.Unmarshal(first, &decoded); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if decoded.Version != tt.want {
+				t.Fatalf("version = %q, want %q", decoded.Version, tt.want)
+			}
+			diffs, err := CheckManifest(first, tt.version)
+			if err != nil || len(diffs) != 0 {
+				t.Fatalf("CheckManifest of generated manifest = %v, %v", diffs, err)
+			}
+		})
+	}
+}
+
+func TestCheckManifestVersion(t *testing.T) {
+	manifest, err := GenerateManifest(nil, "1.0.0")
+	if err != nil {
+		t.Fatalf("GenerateManifest: %v", err)
+	}
+	if diffs, _ := CheckManifest(manifest, ""); len(diffs) != 0 {
+		t.Fatalf("CheckManifest without version = %v, want no diffs", diffs)
+	}
+	diffs, _ := CheckManifest(manifest, "2.0.0")
+	if len(diffs) != 1 || !strings.HasPrefix(diffs[0], "version:") {
+		t.Fatalf("CheckManifest with other version = %v, want one version diff", diffs)
+	}
+}
+
+func TestGenerateManifestKeepsDeclaredRoutes(t *testing.T) {
+	base := []byte(`{"name": "browser", "actions": {
+		"navigate": {"description": "old", "method": "POST", "path": "/v1/browser/actions/navigate", "timeout_ms": 1234},
+		"teleport": {"method": "POST", "path": "/v1/browser/actions/teleport"}
+	}}`)
+	data, err := GenerateManifest(base, "")
+	if err != nil {
+		t.Fatalf("GenerateManifest: %v", err)
+	}
+	var generated pluginspec.Manifest
+	if err := json.Unmarshal(data, &generated); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	defaults := registryManifest("", nil)
+
+	navigate := generated.Actions["navigate"]
+	if navigate.Path != "/v1/browser/actions/navigate" || navigate.TimeoutMs != 1234 {
+		t.Fatalf("navigate = %+v, want the declared route kept", navigate)
+	}
+	if navigate.Description != defaults.Actions["navigate"].Description {
+		t.Fatalf("navigate description = %q, want the registry's", navigate.Description)
+	}
+	if reload := generated.Actions["reload"]; reload != defaults.Actions["reload"] {
+		t.Fatalf("reload = %+v, want registry default %+v", reload, defaults.Actions["reload"])
+	}
+	if _, ok := generated.Actions["teleport"]; ok {
+		t.Fatal("action without a handler was kept")
+	}
+	if diffs, err := CheckManifest(data, ""); err != nil || len(diffs) != 0 {
+		t.Fatalf("CheckManifest of generated manifest = %v, %v", diffs, err)
+	}
+}
+
+func TestCheckManifestReportsMissingRoutes(t *testing.T) {
+	data, err := GenerateManifest(nil, "")
+	if err != nil {
+		t.Fatalf("GenerateManifest: %v", err)
+	}
+	var manifest pluginspec.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	moved := manifest.Actions["navigate"]
+	moved.Path = "/v1/browser/actions/navigate"
+	manifest.Actions["navigate"] = moved
+	blank := manifest.Actions["reload"]
+	blank.Path = ""
+	manifest.Actions["reload"] = blank
+	delete(manifest.Actions, "back")
+	data, err = json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	diffs, err := CheckManifest(data, "")
+	if err != nil {
+		t.Fatalf("CheckManifest: %v", err)
+	}
+	want := []string{
+		"actions.back: missing from manifest",
+		`actions.reload.path: manifest has , registry has ` + registryManifest("", nil).Actions["reload"].Path,
+	}
+	if strings.Join(diffs, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("CheckManifest = %q, want %q", diffs, want)
+	}
+}
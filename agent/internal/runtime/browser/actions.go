@@ -0,0 +1,240 @@
+package browser
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	pluginspec "github.com/ccheshirecat/volant/pkg/pluginspec"
+)
+
+// Action is a named HTTP operation exposed by the agent. Path is the default
+// route; a manifest may declare a different method, path and timeout for the
+// same name.
+type Action struct {
+	Name        string
+	Description string
+	Method      string
+	Path        string
+	TimeoutMs   int64
+	Handler     http.HandlerFunc
+
+	// Request and Response are zero values of the JSON bodies the action
+	// reads and writes, and Query names the query parameters it accepts.
+	// They only feed the OpenAPI document.
+	Request  any
+	Response any
+	Query    []string
+
+	// Stream marks actions that hold the response open, such as event
+	// streams and file downloads. They run without the request timeout and
+	// the server's write deadline.
+	Stream bool
+}
+
+type actionTimeoutKey struct{}
+
+// builtinActions lists every action implemented by the browser runtime.
+func (r *Runtime) builtinActions() []Action {
+	return []Action{
+		{Name: "navigate", Description: "Navigate the browser to a URL", Method: http.MethodPost, Path: "/v1/browser/navigate", TimeoutMs: 60000, Handler: r.handleNavigate, Request: navigateRequest{}, Response: navigateResponse{}},
+		{Name: "reload", Description: "Reload the current page", Method: http.MethodPost, Path: "/v1/browser/reload", TimeoutMs: 30000, Handler: r.handleReload, Request: reloadRequest{}, Response: navigateResponse{}},
+		{Name: "back", Description: "Navigate back in history", Method: http.MethodPost, Path: "/v1/browser/back", TimeoutMs: 15000, Handler: r.handleBack, Response: navigateResponse{}, Query: []string{"target_id", "wait_until", "timeout_ms"}},
+		{Name: "forward", Description: "Navigate forward in history", Method: http.MethodPost, Path: "/v1/browser/forward", TimeoutMs: 15000, Handler: r.handleForward, Response: navigateResponse{}, Query: []string{"target_id", "wait_until", "timeout_ms"}},
+		{Name: "viewport", Description: "Set viewport dimensions", Method: http.MethodPost, Path: "/v1/browser/viewport", TimeoutMs: 30000, Handler: r.handleViewport, Request: viewportRequest{}, Response: statusResponse{}},
+		{Name: "user_agent", Description: "Override the active user agent", Method: http.MethodPost, Path: "/v1/browser/user-agent", TimeoutMs: 30000, Handler: r.handleUserAgent, Request: userAgentRequest{}, Response: statusResponse{}},
+		{Name: "wait_navigation", Description: "Wait for the current navigation to complete", Method: http.MethodPost, Path: "/v1/browser/wait-navigation", TimeoutMs: 60000, Handler: r.handleWaitNavigation, Request: waitNavigationRequest{}, Response: navigateResponse{}},
+		{Name: "screenshot", Description: "Capture a PNG/JPEG screenshot", Method: http.MethodPost, Path: "/v1/browser/screenshot", TimeoutMs: 60000, Handler: r.handleScreenshot, Request: screenshotRequest{}, Response: screenshotResponse{}},
+		{Name: "scrape", Description: "Scrape text or attribute from selector", Method: http.MethodPost, Path: "/v1/browser/scrape", TimeoutMs: 60000, Handler: r.handleScrape, Request: scrapeRequest{}, Response: attributeResponse{}},
+		{Name: "fetch", Description: "Issue an HTTP request from the page with its cookies", Method: http.MethodPost, Path: "/v1/browser/fetch", TimeoutMs: 60000, Handler: r.handleFetch, Request: fetchRequest{}, Response: FetchResponse{}},
+		{Name: "graphql", Description: "Proxy a GraphQL POST request or batch", Method: http.MethodPost, Path: "/v1/browser/graphql", TimeoutMs: 60000, Handler: r.handleGraphQL, Request: graphqlRequest{}, Response: GraphQLResponse{}},
+		{Name: "openapi", Description: "OpenAPI 3 document for the mounted actions", Method: http.MethodGet, Path: "/v1/openapi.json", TimeoutMs: 5000, Handler: r.handleOpenAPI, Response: map[string]any{}},
+		{Name: "queue_stats", Description: "Report action queue depth per target", Method: http.MethodGet, Path: "/v1/browser/queue", TimeoutMs: 5000, Handler: r.handleQueueStats, Response: QueueStats{}},
+		{Name: "tabs_list", Description: "List open tabs", Method: http.MethodGet, Path: "/v1/browser/tabs", TimeoutMs: 15000, Handler: r.handleListTabs, Response: tabsResponse{}, Query: []string{"timeout_ms"}},
+		{Name: "tab_open", Description: "Open a new tab", Method: http.MethodPost, Path: "/v1/browser/tabs", TimeoutMs: 60000, Handler: r.handleOpenTab, Request: newTabRequest{}, Response: TargetInfo{}},
+		{Name: "tab_activate", Description: "Make a tab the default target", Method: http.MethodPost, Path: "/v1/browser/tabs/{targetID}/activate", TimeoutMs: 15000, Handler: r.handleActivateTab, Response: statusResponse{}, Query: []string{"timeout_ms"}},
+		{Name: "tab_close", Description: "Close a tab", Method: http.MethodDelete, Path: "/v1/browser/tabs/{targetID}", TimeoutMs: 15000, Handler: r.handleCloseTab, Response: statusResponse{}, Query: []string{"timeout_ms"}},
+		{Name: "routes_list", Description: "List request interception rules", Method: http.MethodGet, Path: "/v1/network/routes", TimeoutMs: 5000, Handler: r.handleListRoutes, Response: routesResponse{}, Query: []string{"target_id"}},
+		{Name: "route_add", Description: "Block, fulfil, modify or continue matching requests", Method: http.MethodPost, Path: "/v1/network/routes", TimeoutMs: 15000, Handler: r.handleAddRoute, Request: routeRequest{}, Response: RouteRule{}},
+		{Name: "route_delete", Description: "Remove a request interception rule", Method: http.MethodDelete, Path: "/v1/network/routes/{routeID}", TimeoutMs: 15000, Handler: r.handleDeleteRoute, Response: statusResponse{}, Query: []string{"target_id", "timeout_ms"}},
+		{Name: "routes_clear", Description: "Remove all request interception rules", Method: http.MethodDelete, Path: "/v1/network/routes", TimeoutMs: 15000, Handler: r.handleClearRoutes, Response: statusResponse{}, Query: []string{"target_id", "timeout_ms"}},
+		{Name: "blocking_get", Description: "Show the session's resource block policy and counters", Method: http.MethodGet, Path: "/v1/network/blocking", TimeoutMs: 5000, Handler: r.handleGetBlocking, Response: BlockingInfo{}},
+		{Name: "blocking_set", Description: "Block resource types and URL globs for the session", Method: http.MethodPut, Path: "/v1/network/blocking", TimeoutMs: 30000, Handler: r.handleSetBlocking, Request: blockingRequest{}, Response: BlockingInfo{}},
+		{Name: "headers_get", Description: "Show the session's extra HTTP headers and credential user", Method: http.MethodGet, Path: "/v1/network/headers", TimeoutMs: 5000, Handler: r.handleGetHeaders, Response: HeadersInfo{}},
+		{Name: "headers_set", Description: "Replace the extra HTTP headers sent by the session", Method: http.MethodPut, Path: "/v1/network/headers", TimeoutMs: 30000, Handler: r.handleSetHeaders, Request: headersRequest{}, Response: HeadersInfo{}},
+		{Name: "credentials_set", Description: "Answer HTTP auth challenges in the session with these credentials", Method: http.MethodPut, Path: "/v1/network/credentials", TimeoutMs: 30000, Handler: r.handleSetCredentials, Request: credentialsRequest{}, Response: HeadersInfo{}},
+		{Name: "credentials_clear", Description: "Stop answering HTTP auth challenges in the session", Method: http.MethodDelete, Path: "/v1/network/credentials", TimeoutMs: 30000, Handler: r.handleClearCredentials, Query: []string{"timeout_ms"}, Response: HeadersInfo{}},
+		{Name: "throttling_get", Description: "Show the session's network and CPU throttling", Method: http.MethodGet, Path: "/v1/network/throttling", TimeoutMs: 5000, Handler: r.handleGetThrottling, Response: throttlingResponse{}},
+		{Name: "throttling_set", Description: "Throttle the session with a named profile or custom latency, throughput and CPU rate", Method: http.MethodPut, Path: "/v1/network/throttling", TimeoutMs: 30000, Handler: r.handleSetThrottling, Request: throttlingRequest{}, Response: throttlingResponse{}},
+		{Name: "throttling_clear", Description: "Lift the session's throttling", Method: http.MethodDelete, Path: "/v1/network/throttling", TimeoutMs: 30000, Handler: r.handleClearThrottling, Response: throttlingResponse{}, Query: []string{"timeout_ms"}},
+		{Name: "certificates_get", Description: "Show the session's certificate error policy and overridden error count", Method: http.MethodGet, Path: "/v1/network/certificates", TimeoutMs: 5000, Handler: r.handleGetCertificates, Response: CertificateInfo{}},
+		{Name: "certificates_set", Description: "Ignore or enforce certificate errors in the session", Method: http.MethodPut, Path: "/v1/network/certificates", TimeoutMs: 30000, Handler: r.handleSetCertificates, Request: certificatesRequest{}, Response: CertificateInfo{}},
+		{Name: "wait_response", Description: "Wait for a matching response, optionally triggered by a click or navigation, and return its body", Method: http.MethodPost, Path: "/v1/network/wait-response", TimeoutMs: 60000, Handler: r.handleWaitResponse, Request: waitResponseRequest{}, Response: CapturedResponse{}},
+		{Name: "har_start", Description: "Start recording the target's traffic as HAR", Method: http.MethodPost, Path: "/v1/network/har/start", TimeoutMs: 15000, Handler: r.handleHARStart, Request: harStartRequest{}, Response: HARStatus{}},
+		{Name: "har_stop", Description: "Stop the target's HAR recording", Method: http.MethodPost, Path: "/v1/network/har/stop", TimeoutMs: 15000, Handler: r.handleHARStop, Request: targetRequest{}, Response: HARStatus{}},
+		{Name: "har_export", Description: "Download the target's HAR recording", Method: http.MethodGet, Path: "/v1/network/har", TimeoutMs: 30000, Handler: r.handleHARExport, Response: HAR{}, Query: []string{"target_id"}},
+		{Name: "websockets_start", Description: "Start capturing the target's WebSocket frames", Method: http.MethodPost, Path: "/v1/network/websockets/start", TimeoutMs: 15000, Handler: r.handleWebSocketsStart, Request: webSocketsStartRequest{}, Response: WebSocketStatus{}},
+		{Name: "websockets_stop", Description: "Stop capturing the target's WebSocket frames", Method: http.MethodPost, Path: "/v1/network/websockets/stop", TimeoutMs: 15000, Handler: r.handleWebSocketsStop, Request: targetRequest{}, Response: WebSocketStatus{}},
+		{Name: "websocket_frames", Description: "List the target's captured WebSocket frames", Method: http.MethodGet, Path: "/v1/network/websockets", TimeoutMs: 15000, Handler: r.handleWebSocketFrames, Response: webSocketFramesResponse{}, Query: []string{"target_id", "since"}},
+		{Name: "websocket_stream", Description: "Stream the target's WebSocket frames as server-sent events", Method: http.MethodGet, Path: "/v1/network/websockets/stream", TimeoutMs: 60000, Handler: r.handleWebSocketStream, Stream: true, Query: []string{"target_id"}},
+		{Name: "downloads_list", Description: "List the session's downloads", Method: http.MethodGet, Path: "/v1/downloads", TimeoutMs: 5000, Handler: r.handleListDownloads, Response: downloadsResponse{}},
+		{Name: "download_get", Description: "Download a completed file", Method: http.MethodGet, Path: "/v1/downloads/{downloadID}", TimeoutMs: 300000, Handler: r.handleGetDownload, Stream: true},
+		{Name: "download_delete", Description: "Cancel a download if running and delete its file", Method: http.MethodDelete, Path: "/v1/downloads/{downloadID}", TimeoutMs: 15000, Handler: r.handleDeleteDownload, Response: statusResponse{}, Query: []string{"timeout_ms"}},
+		{Name: "click", Description: "Click a DOM element", Method: http.MethodPost, Path: "/v1/dom/click", TimeoutMs: 30000, Handler: r.handleClick, Request: clickRequest{}, Response: statusResponse{}},
+		{Name: "type", Description: "Type into a DOM element", Method: http.MethodPost, Path: "/v1/dom/type", TimeoutMs: 45000, Handler: r.handleType, Request: typeRequest{}, Response: statusResponse{}},
+		{Name: "upload", Description: "Set files on a file input from base64 payloads or the parts of a multipart files field", Method: http.MethodPost, Path: "/v1/dom/upload", TimeoutMs: 60000, Handler: r.handleUpload, Request: uploadRequest{}, Response: uploadResponse{}},
+		{Name: "get_text", Description: "Get text content from a selector", Method: http.MethodPost, Path: "/v1/dom/get-text", TimeoutMs: 45000, Handler: r.handleGetText, Request: textRequest{}, Response: textResponse{}},
+		{Name: "get_html", Description: "Get HTML from a selector", Method: http.MethodPost, Path: "/v1/dom/get-html", TimeoutMs: 45000, Handler: r.handleGetHTML, Request: htmlRequest{}, Response: htmlResponse{}},
+		{Name: "get_attribute", Description: "Get an attribute from a selector", Method: http.MethodPost, Path: "/v1/dom/get-attribute", TimeoutMs: 45000, Handler: r.handleGetAttribute, Request: attributeRequest{}, Response: attributeResponse{}},
+		{Name: "wait_selector", Description: "Wait for a selector to appear/meet criteria", Method: http.MethodPost, Path: "/v1/dom/wait-selector", TimeoutMs: 60000, Handler: r.handleWaitSelector, Request: waitSelectorRequest{}, Response: statusResponse{}},
+		{Name: "evaluate", Description: "Evaluate JavaScript in the page context", Method: http.MethodPost, Path: "/v1/script/evaluate", TimeoutMs: 60000, Handler: r.handleEvaluate, Request: evaluateRequest{}, Response: evaluateResponse{}},
+		{Name: "profile_attach", Description: "Attach profile cookies/storage", Method: http.MethodPost, Path: "/v1/profile/attach", TimeoutMs: 60000, Handler: r.handleProfileAttach, Request: profileAttachRequest{}, Response: statusResponse{}},
+		{Name: "profile_extract", Description: "Extract profile cookies/storage", Method: http.MethodGet, Path: "/v1/profile/extract", TimeoutMs: 30000, Handler: r.handleProfileExtract, Response: profileResponse{}, Query: []string{"target_id", "timeout_ms"}},
+		{Name: "sessions_list", Description: "List isolated browser sessions", Method: http.MethodGet, Path: "/v1/sessions", TimeoutMs: 5000, Handler: r.handleListSessions, Response: sessionsResponse{}},
+		{Name: "session_create", Description: "Create an isolated browser session", Method: http.MethodPost, Path: "/v1/sessions", TimeoutMs: 30000, Handler: r.handleCreateSession, Request: createSessionRequest{}, Response: SessionInfo{}},
+		{Name: "session_delete", Description: "Delete a browser session", Method: http.MethodDelete, Path: "/v1/sessions/{sessionID}", TimeoutMs: 30000, Handler: r.handleDeleteSession, Response: statusResponse{}, Query: []string{"timeout_ms"}},
+	}
+}
+
+// DefaultActions returns the browser runtime's actions at their default
+// routes without starting a browser. Handlers are omitted.
+func DefaultActions() []Action {
+	actions := (&Runtime{}).builtinActions()
+	for i := range actions {
+		actions[i].Handler = nil
+	}
+	return actions
+}
+
+// RegisterAction adds an action served outside the browser runtime (for
+// example the agent's health and log endpoints) so manifests can reference it.
+// It must be called before MountRoutes.
+func (r *Runtime) RegisterAction(action Action) {
+	r.extraActions = append(r.extraActions, action)
+}
+
+// Actions returns the action registry sorted by name.
+func (r *Runtime) Actions() []Action {
+	actions := append(r.builtinActions(), r.extraActions...)
+	sort.Slice(actions, func(i, j int) bool { return actions[i].Name < actions[j].Name })
+	return actions
+}
+
+// resolveActions applies the manifest to the registry. Without a manifest
+// every action is served at its default route. With one, only the declared
+// actions are served, at the manifest's method, path and timeout where given;
+// a declared action without a handler is an error.
+func (r *Runtime) resolveActions(manifest *pluginspec.Manifest) ([]Action, error) {
+	if manifest == nil {
+		return r.Actions(), nil
+	}
+	registry := make(map[string]Action)
+	for _, action := range r.Actions() {
+		registry[action.Name] = action
+	}
+
+	names := make([]string, 0, len(manifest.Actions))
+	for name := range manifest.Actions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resolved := make([]Action, 0, len(names))
+	var missing []string
+	for _, name := range names {
+		declared := manifest.Actions[name]
+		action, ok := registry[name]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		if method := strings.ToUpper(strings.TrimSpace(declared.Method)); method != "" {
+			action.Method = method
+		}
+		if path := strings.TrimSpace(declared.Path); path != "" {
+			if !strings.HasPrefix(path, "/") {
+				return nil, fmt.Errorf("browser runtime: manifest action %q has invalid path %q", name, declared.Path)
+			}
+			action.Path = path
+		}
+		if declared.TimeoutMs > 0 {
+			action.TimeoutMs = declared.TimeoutMs
+		}
+		resolved = append(resolved, action)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("browser runtime: manifest declares actions without handlers: %s", strings.Join(missing, ", "))
+	}
+	return resolved, nil
+}
+
+// withActionTimeout makes the action's timeout the default for requests that
+// do not carry their own timeout_ms.
+func withActionTimeout(action Action) http.HandlerFunc {
+	if action.TimeoutMs <= 0 {
+		return action.Handler
+	}
+	return func(w http.ResponseWriter, req *http.Request) {
+		ctx := context.WithValue(req.Context(), actionTimeoutKey{}, action.TimeoutMs)
+		action.Handler(w, req.WithContext(ctx))
+	}
+}
+
+func mountActions(router chi.Router, actions []Action, requestTimeout time.Duration) {
+	for _, action := range actions {
+		router.Method(action.Method, action.Path, boundAction(action, requestTimeout))
+	}
+}
+
+// boundAction applies the request timeout to an ordinary action. A stream
+// instead lifts the server's write deadline, which would otherwise cut it off
+// mid-response.
+func boundAction(action Action, requestTimeout time.Duration) http.Handler {
+	handler := withActionTimeout(action)
+	if action.Stream {
+		return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			// Writers without deadlines have nothing to lift.
+			_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+			handler(w, req)
+		})
+	}
+	if requestTimeout <= 0 {
+		return handler
+	}
+	return middleware.Timeout(requestTimeout)(handler)
+}
+
+// handleDispatch invokes an action by name rather than by path. Path
+// parameters of the target route (for example {targetID}) are read from the
+// query string.
+func (r *Runtime) handleDispatch(w http.ResponseWriter, req *http.Request) {
+	name := chi.URLParam(req, "name")
+	action, ok := r.dispatch[name]
+	if !ok {
+		errorJSON(w, http.StatusNotFound, fmt.Errorf("browser runtime: unknown action %q", name))
+		return
+	}
+	if rctx := chi.RouteContext(req.Context()); rctx != nil {
+		for _, param := range pathParams(action.Path) {
+			rctx.URLParams.Add(param, req.URL.Query().Get(param))
+		}
+	}
+	boundAction(action, r.requestTimeout).ServeHTTP(w, req)
+}
+
+func pathParams(path string) []string {
+	var params []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			name, _, _ := strings.Cut(strings.Trim(segment, "{}"), ":")
+			params = append(params, name)
+		}
+	}
+	return params
+}
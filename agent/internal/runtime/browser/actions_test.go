@@ -0,0 +1,227 @@
+package browser
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ccheshirecat/volant/pkg/pluginspec"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestPathParams(t *testing.T) {
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"/v1/browser/navigate", nil},
+		{"/v1/browser/tabs/{targetID}", []string{"targetID"}},
+		{"/v1/browser/tabs/{targetID}/activate", []string{"targetID"}},
+		{"/v1/network/routes/{routeID:[a-z0-9]+}", []string{"routeID"}},
+		{"/v1/{a}/x/{b}", []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		if got := pathParams(tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("pathParams(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func findAction(t *testing.T, actions []Action, name string) Action {
+	t.Helper()
+	for _, action := range actions {
+		if action.Name == name {
+			return action
+		}
+	}
+	t.Fatalf("action %q not found", name)
+	return Action{}
+}
+
+func TestResolveActions(t *testing.T) {
+	defaults := (&Runtime{}).Actions()
+	navigate := findAction(t, defaults, "navigate")
+
+	tests := []struct {
+		name     string
+		manifest *pluginspec.Manifest
+		wantErr  string
+		check    func(t *testing.T, actions []Action)
+	}{
+		{
+			name: "no manifest keeps defaults",
+			check: func(t *testing.T, actions []Action) {
+				if len(actions) != len(defaults) {
+					t.Fatalf("got %d actions, want %d", len(actions), len(defaults))
+				}
+				if got := findAction(t, actions, "navigate"); got.Path != navigate.Path || got.Method != navigate.Method {
+					t.Fatalf("navigate resolved to %s %s", got.Method, got.Path)
+				}
+			},
+		},
+		{
+			name: "declared route and timeout win",
+			manifest: &pluginspec.Manifest{Actions: map[string]pluginspec.Action{
+				"navigate": {Method: "put", Path: " /custom/navigate ", TimeoutMs: 1234},
+			}},
+			check: func(t *testing.T, actions []Action) {
+				got := findAction(t, actions, "navigate")
+				if got.Method != http.MethodPut || got.Path != "/custom/navigate" || got.TimeoutMs != 1234 {
+					t.Fatalf("navigate resolved to %s %s (%dms)", got.Method, got.Path, got.TimeoutMs)
+				}
+				if len(actions) != 1 {
+					t.Fatalf("got %d actions, want only the declared one", len(actions))
+				}
+			},
+		},
+		{
+			name: "empty declaration keeps defaults",
+			manifest: &pluginspec.Manifest{Actions: map[string]pluginspec.Action{
+				"navigate": {},
+			}},
+			check: func(t *testing.T, actions []Action) {
+				got := findAction(t, actions, "navigate")
+				if got.Method != navigate.Method || got.Path != navigate.Path || got.TimeoutMs != navigate.TimeoutMs {
+					t.Fatalf("navigate resolved to %s %s (%dms)", got.Method, got.Path, got.TimeoutMs)
+				}
+			},
+		},
+		{
+			name: "relative path",
+			manifest: &pluginspec.Manifest{Actions: map[string]pluginspec.Action{
+				"navigate": {Path: "v1/navigate"},
+			}},
+			wantErr: `invalid path "v1/navigate"`,
+		},
+		{
+			name: "declared without handler",
+			manifest: &pluginspec.Manifest{Actions: map[string]pluginspec.Action{
+				"teleport": {}, "navigate": {}, "levitate": {},
+			}},
+			wantErr: "without handlers: levitate, teleport",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actions, err := (&Runtime{}).resolveActions(tt.manifest)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("resolveActions() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveActions() error = %v", err)
+			}
+			tt.check(t, actions)
+		})
+	}
+}
+
+func TestDispatchOnlyDeclaredActions(t *testing.T) {
+	echo := func(w http.ResponseWriter, req *http.Request) {
+		respondJSON(w, http.StatusOK, map[string]string{"target": chi.URLParam(req, "targetID")})
+	}
+	r := &Runtime{manifest: &pluginspec.Manifest{Actions: map[string]pluginspec.Action{
+		"echo": {},
+	}}}
+	r.RegisterAction(Action{Name: "echo", Method: http.MethodGet, Path: "/echo/{targetID}", Handler: echo})
+	r.RegisterAction(Action{Name: "hidden", Method: http.MethodGet, Path: "/hidden", Handler: echo})
+	router := chi.NewRouter()
+	if err := r.MountRoutes(router); err != nil {
+		t.Fatalf("MountRoutes: %v", err)
+	}
+
+	tests := []struct {
+		action string
+		want   int
+		body   string
+	}{
+		{"echo?targetID=abc", http.StatusOK, `"target":"abc"`},
+		{"hidden", http.StatusNotFound, "unknown action"},
+		{"navigate", http.StatusNotFound, "unknown action"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/actions/"+tt.action, nil))
+		if rec.Code != tt.want || !strings.Contains(rec.Body.String(), tt.body) {
+			t.Errorf("dispatch %s = %d %s, want %d containing %q", tt.action, rec.Code, rec.Body.String(), tt.want, tt.body)
+		}
+	}
+
+	// Undeclared actions are not served at their own routes either.
+	for path, want := range map[string]int{"/echo/abc": http.StatusOK, "/hidden": http.StatusNotFound} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != want {
+			t.Errorf("GET %s = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
+
+func TestStreamActionsOutliveRequestTimeout(t *testing.T) {
+	const limit = 50 * time.Millisecond
+	slow := func(w http.ResponseWriter, req *http.Request) {
+		select {
+		case <-time.After(3 * limit):
+			respondJSON(w, http.StatusOK, statusResponse{Status: "ok"})
+		case <-req.Context().Done():
+		}
+	}
+	stream := func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < 3; i++ {
+			time.Sleep(limit)
+			if req.Context().Err() != nil {
+				return
+			}
+			fmt.Fprintf(w, "data: %d\n\n", i)
+			w.(http.Flusher).Flush()
+		}
+	}
+	r := &Runtime{requestTimeout: limit}
+	r.RegisterAction(Action{Name: "slow", Method: http.MethodGet, Path: "/slow", Handler: slow})
+	r.RegisterAction(Action{Name: "stream", Method: http.MethodGet, Path: "/stream", Handler: stream, Stream: true})
+	router := chi.NewRouter()
+	if err := r.MountRoutes(router); err != nil {
+		t.Fatalf("MountRoutes: %v", err)
+	}
+	server := httptest.NewUnstartedServer(router)
+	// Streams run past both the request timeout and the write deadline.
+	server.Config.WriteTimeout = 2 * limit
+	server.Start()
+	defer server.Close()
+
+	tests := []struct {
+		method, path string
+		want         int
+		body         string
+	}{
+		{http.MethodGet, "/slow", http.StatusGatewayTimeout, ""},
+		{http.MethodPost, "/v1/actions/slow", http.StatusGatewayTimeout, ""},
+		{http.MethodGet, "/stream", http.StatusOK, "data: 0\n\ndata: 1\n\ndata: 2\n\n"},
+		{http.MethodPost, "/v1/actions/stream", http.StatusOK, "data: 0\n\ndata: 1\n\ndata: 2\n\n"},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, server.URL+tt.path, nil)
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Errorf("%s %s: %v", tt.method, tt.path, err)
+			continue
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Errorf("%s %s: read body: %v", tt.method, tt.path, err)
+			continue
+		}
+		if resp.StatusCode != tt.want || (tt.body != "" && string(body) != tt.body) {
+			t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.path, resp.StatusCode, body, tt.want, tt.body)
+		}
+	}
+}
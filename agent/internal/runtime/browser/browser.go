@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
@@ -22,6 +23,7 @@ import (
 	"github.com/chromedp/cdproto/page"
 	cpruntime "github.com/chromedp/cdproto/runtime"
 	"github.com/chromedp/cdproto/storage"
+	"github.com/chromedp/cdproto/target"
 	"github.com/chromedp/chromedp"
 )
 
@@ -69,6 +71,12 @@ type BrowserConfig struct {
 	UserDataDir         string
 	ExecPath            string
 	DefaultTimeout      time.Duration
+	SessionIdleTimeout  time.Duration
+	MaxConcurrency      int
+	QueueSize           int
+	BlockPolicy         BlockPolicy
+	Proxy               ProxyConfig
+	Certificates        CertificatePolicy
 }
 
 // StoragePayload captures localStorage/sessionStorage key/value pairs.
@@ -98,16 +106,39 @@ type devToolsInternal struct {
 // provides higher-level automation helpers.
 type Browser struct {
 	cfg                BrowserConfig
-	chrome             *exec.Cmd
-	ctx                context.Context
-	cancel             context.CancelFunc
+	lifetime           context.Context
+	cancelLifetime     context.CancelFunc
 	port               int
 	userDataDir        string
 	cleanupUserDataDir bool
 
-	mu       sync.Mutex
-	log      *logEmitter
-	devtools devToolsInternal
+	log     *logEmitter
+	workers chan struct{}
+
+	// connMu guards the Chrome process and the chromedp connection, both of
+	// which are replaced by the supervisor when Chrome is relaunched.
+	connMu     sync.RWMutex
+	chrome     *exec.Cmd
+	exited     chan struct{}
+	ctx        context.Context
+	cancel     context.CancelFunc
+	devtools   devToolsInternal
+	closing    bool
+	restarting bool
+	startedAt  time.Time
+	restarts   int
+	lastCrash  time.Time
+	crashCause string
+
+	tabsMu sync.RWMutex
+	tabs   map[target.ID]*tab
+	active map[string]target.ID
+
+	sessionsMu sync.RWMutex
+	sessions   map[string]*session
+
+	policiesMu sync.Mutex
+	policies   map[string]*sessionPolicy
 }
 
 // NewBrowser launches a headless Chrome instance reachable through chromedp.
@@ -127,7 +158,6 @@ func NewBrowser(ctx context.Context, cfg BrowserConfig) (*Browser, error) {
 	if strings.TrimSpace(cfg.RemoteDebuggingAddr) == "" {
 		cfg.RemoteDebuggingAddr = DefaultRemoteAddr
 	}
-	connectHost := devtoolsConnectHost(cfg.RemoteDebuggingAddr)
 
 	cleanupUserDataDir := false
 	if strings.TrimSpace(cfg.UserDataDir) == "" {
@@ -152,10 +182,63 @@ func NewBrowser(ctx context.Context, cfg BrowserConfig) (*Browser, error) {
 	if cfg.DefaultTimeout <= 0 {
 		cfg.DefaultTimeout = DefaultActionTimeout
 	}
+	if cfg.SessionIdleTimeout <= 0 {
+		cfg.SessionIdleTimeout = DefaultSessionIdleTimeout
+	}
+	if cfg.MaxConcurrency <= 0 {
+		cfg.MaxConcurrency = DefaultMaxConcurrency
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultQueueSize
+	}
+	blockPolicy, err := cfg.BlockPolicy.normalize()
+	if err != nil {
+		return nil, err
+	}
+	cfg.BlockPolicy = blockPolicy
+	proxy, err := cfg.Proxy.normalize()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Proxy = proxy
+	certificates, err := cfg.Certificates.normalize()
+	if err != nil {
+		return nil, err
+	}
+	cfg.Certificates = certificates
+
+	lifetime, cancelLifetime := context.WithCancel(ctx)
+	b := &Browser{
+		cfg:                cfg,
+		lifetime:           lifetime,
+		cancelLifetime:     cancelLifetime,
+		log:                newLogEmitter(),
+		port:               cfg.RemoteDebuggingPort,
+		userDataDir:        cfg.UserDataDir,
+		cleanupUserDataDir: cleanupUserDataDir,
+		tabs:               make(map[target.ID]*tab),
+		active:             make(map[string]target.ID),
+		sessions:           make(map[string]*session),
+		policies:           make(map[string]*sessionPolicy),
+		workers:            make(chan struct{}, cfg.MaxConcurrency),
+	}
 
-	logEmitter := newLogEmitter()
+	if err := b.launch(); err != nil {
+		cancelLifetime()
+		return nil, err
+	}
+	go b.reapSessions()
+	return b, nil
+}
+
+// launch starts Chrome, waits for its DevTools endpoint and connects chromedp
+// to it, registering the initial tab. It is used both at startup and by the
+// supervisor when Chrome has to be relaunched.
+func (b *Browser) launch() error {
+	cfg := b.cfg
+	connectHost := devtoolsConnectHost(cfg.RemoteDebuggingAddr)
 
-	cmd := exec.CommandContext(ctx, cfg.ExecPath,
+	args := []string{
 		"--disable-gpu",
 		"--disable-dev-shm-usage",
 		"--headless",
@@ -167,36 +250,33 @@ func NewBrowser(ctx context.Context, cfg BrowserConfig) (*Browser, error) {
 		fmt.Sprintf("--remote-debugging-address=%s", cfg.RemoteDebuggingAddr),
 		fmt.Sprintf("--remote-debugging-port=%d", cfg.RemoteDebuggingPort),
 		fmt.Sprintf("--user-data-dir=%s", cfg.UserDataDir),
-	)
+	}
+	args = append(args, cfg.Proxy.flags()...)
+	args = append(args, cfg.Certificates.flags()...)
+	cmd := exec.CommandContext(b.lifetime, cfg.ExecPath, args...)
 	cmd.Env = append(os.Environ(), fmt.Sprintf("TMPDIR=%s", cfg.UserDataDir))
-	cmd.Stdout = &logWriter{stream: "browser", emitter: logEmitter}
-	cmd.Stderr = &logWriter{stream: "browser", emitter: logEmitter}
+	cmd.Stdout = &logWriter{stream: "browser", emitter: b.log}
+	cmd.Stderr = &logWriter{stream: "browser", emitter: b.log}
 
 	if err := cmd.Start(); err != nil {
-		return nil, fmt.Errorf("browser: start chrome: %w", err)
+		return fmt.Errorf("browser: start chrome: %w", err)
 	}
 
-	startupCtx, cancelStartup := context.WithTimeout(ctx, devtoolsStartupTimeout)
+	startupCtx, cancelStartup := context.WithTimeout(b.lifetime, devtoolsStartupTimeout)
 	defer cancelStartup()
 
-	// Observe unexpected exits.
-	go func() {
-		if waitErr := cmd.Wait(); waitErr != nil {
-			logEmitter.Publish(LogEvent{
-				Stream:    "browser",
-				Line:      fmt.Sprintf("chrome exited unexpectedly: %v", waitErr),
-				Timestamp: time.Now().UTC(),
-			})
-		}
-	}()
+	// Observe exits; the supervisor relaunches Chrome if this process was
+	// the live one.
+	exited := make(chan struct{})
+	go b.supervise(cmd, exited)
 
-	devtoolsInfo, err := waitForDevTools(startupCtx, logEmitter, connectHost, cfg.RemoteDebuggingPort)
+	devtoolsInfo, err := waitForDevTools(startupCtx, b.log, connectHost, cfg.RemoteDebuggingPort)
 	if err != nil {
 		_ = cmd.Process.Kill()
-		return nil, fmt.Errorf("browser: devtools startup: %w", err)
+		return fmt.Errorf("browser: devtools startup: %w", err)
 	}
 
-	remoteAllocatorCtx, cancelAllocator := chromedp.NewRemoteAllocator(ctx, fmt.Sprintf("http://%s/json", net.JoinHostPort(connectHost, strconv.Itoa(cfg.RemoteDebuggingPort))))
+	remoteAllocatorCtx, cancelAllocator := chromedp.NewRemoteAllocator(b.lifetime, fmt.Sprintf("http://%s/json", net.JoinHostPort(connectHost, strconv.Itoa(cfg.RemoteDebuggingPort))))
 
 	browserCtx, cancelCtx := chromedp.NewContext(remoteAllocatorCtx)
 
@@ -204,7 +284,7 @@ func NewBrowser(ctx context.Context, cfg BrowserConfig) (*Browser, error) {
 		cancelCtx()
 		cancelAllocator()
 		_ = cmd.Process.Kill()
-		return nil, fmt.Errorf("browser: enable network: %w", err)
+		return fmt.Errorf("browser: enable network: %w", err)
 	}
 
 	combinedCancel := func() {
@@ -212,31 +292,48 @@ func NewBrowser(ctx context.Context, cfg BrowserConfig) (*Browser, error) {
 		cancelAllocator()
 	}
 
-	b := &Browser{
-		cfg:                cfg,
-		chrome:             cmd,
-		ctx:                browserCtx,
-		cancel:             combinedCancel,
-		log:                logEmitter,
-		devtools:           devtoolsInfo,
-		port:               cfg.RemoteDebuggingPort,
-		userDataDir:        cfg.UserDataDir,
-		cleanupUserDataDir: cleanupUserDataDir,
-	}
+	b.connMu.Lock()
+	b.chrome = cmd
+	b.exited = exited
+	b.ctx = browserCtx
+	b.cancel = combinedCancel
+	b.devtools = devtoolsInfo
+	b.startedAt = time.Now().UTC()
+	b.restarting = false
+	b.connMu.Unlock()
+
+	b.registerTab(&tab{
+		id:      chromedp.FromContext(browserCtx).Target.TargetID,
+		ctx:     browserCtx,
+		created: time.Now().UTC(),
+	})
+	b.watchTargets(browserCtx)
 
-	b.publish("agent", fmt.Sprintf("headless browser ready (exec=%s, devtools 127.0.0.1:%d, profile=%s)", cfg.ExecPath, cfg.RemoteDebuggingPort, cfg.UserDataDir))
-	return b, nil
+	b.publish("agent", fmt.Sprintf("headless browser ready (exec=%s, pid=%d, devtools 127.0.0.1:%d, profile=%s)", cfg.ExecPath, cmd.Process.Pid, cfg.RemoteDebuggingPort, cfg.UserDataDir))
+	return nil
 }
 
 // Close tears down the browser process and resources.
 func (b *Browser) Close() {
-	b.cancel()
-	if b.chrome != nil && b.chrome.Process != nil {
-		_ = b.chrome.Process.Kill()
-		_, _ = b.chrome.Process.Wait()
+	b.connMu.Lock()
+	b.closing = true
+	chrome, exited, cancel := b.chrome, b.exited, b.cancel
+	b.connMu.Unlock()
+
+	b.resetTargets()
+	if cancel != nil {
+		cancel()
+	}
+	b.cancelLifetime()
+	if chrome != nil && chrome.Process != nil {
+		_ = chrome.Process.Kill()
+		<-exited
 	}
 	if b.cleanupUserDataDir && b.userDataDir != "" {
 		_ = os.RemoveAll(b.userDataDir)
+	} else if b.cfg.UserDataDir != "" {
+		// A kept profile keeps its downloads but not staged uploads.
+		_ = os.RemoveAll(filepath.Join(b.cfg.UserDataDir, uploadsDirName))
 	}
 	b.log.Close()
 }
@@ -258,6 +355,8 @@ func allocateFreePort() (int, error) {
 
 // DevToolsInfo returns debugging metadata.
 func (b *Browser) DevToolsInfo() DevToolsInfo {
+	b.connMu.RLock()
+	defer b.connMu.RUnlock()
 	return DevToolsInfo{
 		WebSocketURL:   b.devtools.WebSocketURL,
 		WebSocketPath:  b.devtools.WebSocketPath,
@@ -273,56 +372,169 @@ func (b *Browser) SubscribeLogs(buffer int) (<-chan LogEvent, func()) {
 	return b.log.Subscribe(buffer)
 }
 
-// Navigate opens the requested URL.
-func (b *Browser) Navigate(timeout time.Duration, url string) error {
-	return b.run(timeout, "navigate", fmt.Sprintf("Navigating to %s", url), func(ctx context.Context) (string, error) {
-		if err := chromedp.Run(ctx, chromedp.Navigate(url)); err != nil {
+// NavigateOptions adjust a single navigation. Headers are merged over the
+// session's extra headers and credentials take precedence over the session's
+// until the navigation finishes. WaitUntil picks the condition to wait for,
+// load by default. FailOnStatus turns a 4xx or 5xx document into an
+// ErrHTTPStatus error. Block refuses requests on top of the session's block
+// policy until the navigation finishes; they count towards its stats.
+type NavigateOptions struct {
+	Headers      map[string]string
+	Credentials  *Credentials
+	Block        *BlockPolicy
+	WaitUntil    string
+	FailOnStatus bool
+}
+
+// Navigate opens the requested URL, waits for the page to reach the wait
+// condition and describes the document it ended on. The result is returned
+// alongside ErrHTTPStatus.
+func (b *Browser) Navigate(targetID string, timeout time.Duration, url string, opts NavigateOptions) (NavigationResult, error) {
+	until, err := parseWaitUntil(opts.WaitUntil)
+	if err != nil {
+		return NavigationResult{}, err
+	}
+	headers, err := normalizeHeaders(opts.Headers)
+	if err != nil {
+		return NavigationResult{}, err
+	}
+	if opts.Credentials != nil && opts.Credentials.Username == "" {
+		return NavigationResult{}, errors.New("browser: username is required")
+	}
+	var block *blockRules
+	if opts.Block != nil {
+		policy, err := opts.Block.normalize()
+		if err != nil {
+			return NavigationResult{}, err
+		}
+		block = compileBlock(policy)
+	}
+	t, err := b.tab(targetID)
+	if err != nil {
+		return NavigationResult{}, err
+	}
+	var result NavigationResult
+	err = b.run(targetID, timeout, "navigate", fmt.Sprintf("Navigating to %s", url), func(ctx context.Context) (string, error) {
+		// Per-navigation settings are undone on the tab context so that they
+		// are restored even when the action deadline has passed.
+		if len(headers) > 0 {
+			sp := b.policy(t.session)
+			if err := chromedp.Run(ctx, network.SetExtraHTTPHeaders(sp.extraHeaders(headers))); err != nil {
+				return "", err
+			}
+			defer func() {
+				_ = chromedp.Run(t.ctx, network.SetExtraHTTPHeaders(sp.extraHeaders(nil)))
+			}()
+		}
+		if opts.Credentials != nil {
+			t.intercept.setAuth(opts.Credentials)
+			defer func() {
+				t.intercept.setAuth(nil)
+				_ = b.syncFetch(t.ctx, t)
+			}()
+			if err := b.syncFetch(ctx, t); err != nil {
+				return "", err
+			}
+		}
+		if !block.empty() {
+			t.intercept.setBlock(block)
+			defer func() {
+				t.intercept.setBlock(nil)
+				_ = b.syncFetch(t.ctx, t)
+			}()
+			if err := b.syncFetch(ctx, t); err != nil {
+				return "", err
+			}
+		}
+		var err error
+		result, err = followNavigation(ctx, until, func(ctx context.Context) (cdp.LoaderID, bool, error) {
+			_, loaderID, errorText, _, err := page.Navigate(url).Do(ctx)
+			if err == nil && errorText != "" {
+				err = fmt.Errorf("browser: page load error %s", errorText)
+			}
+			return loaderID, true, err
+		})
+		if err != nil {
 			return "", err
 		}
-		return "navigation committed", nil
+		if opts.FailOnStatus && result.Status >= 400 {
+			return "", fmt.Errorf("%w: %d %s from %s", ErrHTTPStatus, result.Status, result.StatusText, result.URL)
+		}
+		return fmt.Sprintf("loaded %s (%d)", result.URL, result.Status), nil
 	})
+	return result, err
 }
 
-// Reload refreshes the current page.
-func (b *Browser) Reload(timeout time.Duration, ignoreCache bool) error {
-	return b.run(timeout, "reload", "Reloading current page", func(ctx context.Context) (string, error) {
-		action := page.Reload().WithIgnoreCache(ignoreCache)
-		if err := action.Do(ctx); err != nil {
+// Reload refreshes the current page and waits for the wait condition.
+func (b *Browser) Reload(targetID string, timeout time.Duration, ignoreCache bool, waitUntil string) (NavigationResult, error) {
+	until, err := parseWaitUntil(waitUntil)
+	if err != nil {
+		return NavigationResult{}, err
+	}
+	var result NavigationResult
+	err = b.run(targetID, timeout, "reload", "Reloading current page", func(ctx context.Context) (string, error) {
+		var err error
+		result, err = followNavigation(ctx, until, func(ctx context.Context) (cdp.LoaderID, bool, error) {
+			return "", false, page.Reload().WithIgnoreCache(ignoreCache).Do(ctx)
+		})
+		if err != nil {
 			return "", err
 		}
 		return "reload completed", nil
 	})
+	return result, err
 }
 
-// Back navigates back in history.
-func (b *Browser) Back(timeout time.Duration) error {
-	return b.run(timeout, "history_back", "Navigating back", func(ctx context.Context) (string, error) {
-		if err := chromedp.Run(ctx, chromedp.NavigateBack()); err != nil {
-			return "", err
-		}
-		return "back navigation completed", nil
-	})
+// Back navigates back in history and waits for the wait condition.
+func (b *Browser) Back(targetID string, timeout time.Duration, waitUntil string) (NavigationResult, error) {
+	return b.history(targetID, timeout, -1, waitUntil)
+}
+
+// Forward navigates forward in history and waits for the wait condition.
+func (b *Browser) Forward(targetID string, timeout time.Duration, waitUntil string) (NavigationResult, error) {
+	return b.history(targetID, timeout, 1, waitUntil)
 }
 
-// Forward navigates forward in history.
-func (b *Browser) Forward(timeout time.Duration) error {
-	return b.run(timeout, "history_forward", "Navigating forward", func(ctx context.Context) (string, error) {
-		if err := chromedp.Run(ctx, chromedp.NavigateForward()); err != nil {
+func (b *Browser) history(targetID string, timeout time.Duration, delta int64, waitUntil string) (NavigationResult, error) {
+	until, err := parseWaitUntil(waitUntil)
+	if err != nil {
+		return NavigationResult{}, err
+	}
+	name, line, done := "history_back", "Navigating back", "back navigation completed"
+	if delta > 0 {
+		name, line, done = "history_forward", "Navigating forward", "forward navigation completed"
+	}
+	var result NavigationResult
+	err = b.run(targetID, timeout, name, line, func(ctx context.Context) (string, error) {
+		var err error
+		result, err = followNavigation(ctx, until, func(ctx context.Context) (cdp.LoaderID, bool, error) {
+			current, entries, err := page.GetNavigationHistory().Do(ctx)
+			if err != nil {
+				return "", false, err
+			}
+			index := current + delta
+			if index < 0 || index >= int64(len(entries)) {
+				return "", false, errors.New("browser: no history entry in that direction")
+			}
+			return "", false, page.NavigateToHistoryEntry(entries[index].ID).Do(ctx)
+		})
+		if err != nil {
 			return "", err
 		}
-		return "forward navigation completed", nil
+		return done, nil
 	})
+	return result, err
 }
 
 // SetViewport updates viewport dimensions and scale.
-func (b *Browser) SetViewport(timeout time.Duration, width, height int, scale float64, mobile bool) error {
+func (b *Browser) SetViewport(targetID string, timeout time.Duration, width, height int, scale float64, mobile bool) error {
 	if width <= 0 || height <= 0 {
 		return fmt.Errorf("viewport dimensions must be greater than zero")
 	}
 	if scale <= 0 {
 		scale = 1
 	}
-	return b.run(timeout, "set_viewport", fmt.Sprintf("Setting viewport to %dx%d (scale %.2f)", width, height, scale), func(ctx context.Context) (string, error) {
+	return b.run(targetID, timeout, "set_viewport", fmt.Sprintf("Setting viewport to %dx%d (scale %.2f)", width, height, scale), func(ctx context.Context) (string, error) {
 		params := emulation.SetDeviceMetricsOverride(int64(width), int64(height), scale, mobile)
 		if err := params.Do(ctx); err != nil {
 			return "", err
@@ -332,11 +544,11 @@ func (b *Browser) SetViewport(timeout time.Duration, width, height int, scale fl
 }
 
 // SetUserAgent overrides the browser user agent metadata.
-func (b *Browser) SetUserAgent(timeout time.Duration, ua, acceptLanguage, platform string) error {
+func (b *Browser) SetUserAgent(targetID string, timeout time.Duration, ua, acceptLanguage, platform string) error {
 	if strings.TrimSpace(ua) == "" {
 		return errors.New("user agent must not be empty")
 	}
-	return b.run(timeout, "set_user_agent", fmt.Sprintf("Setting user agent: %s", ua), func(ct context.Context) (string, error) {
+	return b.run(targetID, timeout, "set_user_agent", fmt.Sprintf("Setting user agent: %s", ua), func(ct context.Context) (string, error) {
 		params := emulation.SetUserAgentOverride(ua)
 		if acceptLanguage != "" {
 			params = params.WithAcceptLanguage(acceptLanguage)
@@ -351,51 +563,34 @@ func (b *Browser) SetUserAgent(timeout time.Duration, ua, acceptLanguage, platfo
 	})
 }
 
-// WaitForNavigation blocks until the current navigation finishes loading.
-func (b *Browser) WaitForNavigation(timeout time.Duration) error {
-	return b.run(timeout, "wait_for_navigation", "Waiting for navigation to complete", func(ctx context.Context) (string, error) {
-		listener := make(chan struct{}, 1)
-		lctx, cancel := context.WithCancel(ctx)
-		defer cancel()
-
-		chromedp.ListenTarget(lctx, func(ev any) {
-			switch evt := ev.(type) {
-			case *page.EventLifecycleEvent:
-				if evt.Name == "DOMContentLoaded" || evt.Name == "load" {
-					select {
-					case listener <- struct{}{}:
-					default:
-					}
-				}
-			case *page.EventLoadEventFired, *page.EventDomContentEventFired, *page.EventNavigatedWithinDocument, *page.EventFrameNavigated:
-				select {
-				case listener <- struct{}{}:
-				default:
-				}
-			}
+// WaitForNavigation waits for the tab's navigation in progress, or the next
+// one, to reach the wait condition and describes the document it ended on.
+func (b *Browser) WaitForNavigation(targetID string, timeout time.Duration, waitUntil string) (NavigationResult, error) {
+	until, err := parseWaitUntil(waitUntil)
+	if err != nil {
+		return NavigationResult{}, err
+	}
+	var result NavigationResult
+	err = b.run(targetID, timeout, "wait_for_navigation", "Waiting for navigation to complete", func(ctx context.Context) (string, error) {
+		var err error
+		result, err = followNavigation(ctx, until, func(context.Context) (cdp.LoaderID, bool, error) {
+			return "", false, nil
 		})
-
-		timer := time.NewTimer(b.timeout(timeout))
-		defer timer.Stop()
-
-		select {
-		case <-listener:
-			return "navigation finished", nil
-		case <-timer.C:
-			return "", errors.New("navigation timed out")
-		case <-ctx.Done():
-			return "", ctx.Err()
+		if err != nil {
+			return "", err
 		}
+		return fmt.Sprintf("navigation finished at %s (%d)", result.URL, result.Status), nil
 	})
+	return result, err
 }
 
 // Evaluate executes arbitrary JavaScript within the current document context.
-func (b *Browser) Evaluate(timeout time.Duration, expression string, awaitPromise bool) (interface{}, error) {
+func (b *Browser) Evaluate(targetID string, timeout time.Duration, expression string, awaitPromise bool) (interface{}, error) {
 	if strings.TrimSpace(expression) == "" {
 		return nil, errors.New("expression required")
 	}
 	var result interface{}
-	err := b.run(timeout, "evaluate", truncateForLog(fmt.Sprintf("Evaluating script: %s", expression), 120), func(ctx context.Context) (string, error) {
+	err := b.run(targetID, timeout, "evaluate", truncateForLog(fmt.Sprintf("Evaluating script: %s", expression), 120), func(ctx context.Context) (string, error) {
 		eval := cpruntime.Evaluate(expression).WithReturnByValue(true)
 		if awaitPromise {
 			eval = eval.WithAwaitPromise(true)
@@ -414,103 +609,8 @@ func (b *Browser) Evaluate(timeout time.Duration, expression string, awaitPromis
 	return result, err
 }
 
-// GraphQL executes a GraphQL POST using the browser context, preserving session data.
-func (b *Browser) GraphQL(timeout time.Duration, endpoint, query string, variables map[string]any) (map[string]any, error) {
-	if strings.TrimSpace(endpoint) == "" {
-		return nil, errors.New("endpoint required")
-	}
-	if strings.TrimSpace(query) == "" {
-		return nil, errors.New("query required")
-	}
-	if variables == nil {
-		variables = map[string]any{}
-	}
-
-	payload := map[string]any{
-		"query":     query,
-		"variables": variables,
-	}
-	bodyBytes, err := json.Marshal(payload)
-	if err != nil {
-		return nil, err
-	}
-
-	endpointLiteral := jsString(endpoint)
-	bodyLiteral := jsString(string(bodyBytes))
-
-	var raw map[string]any
-	err = b.run(timeout, "graphql", truncateForLog(fmt.Sprintf("GraphQL POST %s", endpoint), 120), func(ctx context.Context) (string, error) {
-		expression := fmt.Sprintf(`(async () => {
-			const response = await fetch(%s, {
-				method: "POST",
-				headers: {"Content-Type": "application/json"},
-				body: %s,
-				credentials: "include",
-			});
-			const headers = {};
-			response.headers.forEach((value, key) => { headers[key] = value; });
-			const text = await response.text();
-			let jsonValue = null;
-			try { jsonValue = JSON.parse(text); } catch (err) { jsonValue = null; }
-			return {
-				status: response.status,
-				ok: response.ok,
-				headers,
-				text,
-				json: jsonValue,
-			};
-		})()`, endpointLiteral, bodyLiteral)
-
-		remote, _, evalErr := cpruntime.Evaluate(expression).
-			WithReturnByValue(true).
-			WithAwaitPromise(true).
-			Do(ctx)
-		if evalErr != nil {
-			return "", evalErr
-		}
-		value, decodeErr := decodeRemoteObject(remote)
-		if decodeErr != nil {
-			return "", decodeErr
-		}
-		converted, ok := value.(map[string]any)
-		if !ok {
-			return "", fmt.Errorf("graphql unexpected result type %T", value)
-		}
-		raw = converted
-		return "graphql executed", nil
-	})
-	if err != nil {
-		return nil, err
-	}
-
-	response := map[string]any{}
-	if status, ok := raw["status"].(float64); ok {
-		response["status"] = int(status)
-	}
-	if okValue, ok := raw["ok"].(bool); ok {
-		response["ok"] = okValue
-	}
-	if text, ok := raw["text"].(string); ok {
-		response["text"] = text
-	}
-	if jsonValue, exists := raw["json"]; exists {
-		response["json"] = jsonValue
-	}
-	if headersRaw, ok := raw["headers"].(map[string]any); ok {
-		headers := make(map[string]string, len(headersRaw))
-		for key, value := range headersRaw {
-			if str, ok := value.(string); ok {
-				headers[key] = str
-			}
-		}
-		response["headers"] = headers
-	}
-
-	return response, nil
-}
-
 // Screenshot captures a screenshot and returns the raw bytes.
-func (b *Browser) Screenshot(timeout time.Duration, fullPage bool, format string, quality int) ([]byte, error) {
+func (b *Browser) Screenshot(targetID string, timeout time.Duration, fullPage bool, format string, quality int) ([]byte, error) {
 	if quality <= 0 || quality > 100 {
 		quality = 90
 	}
@@ -520,7 +620,7 @@ func (b *Browser) Screenshot(timeout time.Duration, fullPage bool, format string
 	format = strings.ToLower(format)
 
 	var data []byte
-	err := b.run(timeout, "screenshot", fmt.Sprintf("Capturing screenshot (full=%t, format=%s)", fullPage, format), func(ctx context.Context) (string, error) {
+	err := b.run(targetID, timeout, "screenshot", fmt.Sprintf("Capturing screenshot (full=%t, format=%s)", fullPage, format), func(ctx context.Context) (string, error) {
 		var captureErr error
 		switch {
 		case fullPage && format == "png":
@@ -573,8 +673,8 @@ func (b *Browser) Screenshot(timeout time.Duration, fullPage bool, format string
 }
 
 // SetCookies sets browser cookies.
-func (b *Browser) SetCookies(timeout time.Duration, cookies []*network.CookieParam) error {
-	return b.run(timeout, "set_cookies", fmt.Sprintf("Setting %d cookie(s)", len(cookies)), func(ctx context.Context) (string, error) {
+func (b *Browser) SetCookies(targetID string, timeout time.Duration, cookies []*network.CookieParam) error {
+	return b.run(targetID, timeout, "set_cookies", fmt.Sprintf("Setting %d cookie(s)", len(cookies)), func(ctx context.Context) (string, error) {
 		if len(cookies) == 0 {
 			if err := network.ClearBrowserCookies().Do(ctx); err != nil {
 				return "", err
@@ -589,9 +689,9 @@ func (b *Browser) SetCookies(timeout time.Duration, cookies []*network.CookiePar
 }
 
 // GetCookies retrieves all browser cookies.
-func (b *Browser) GetCookies(timeout time.Duration) ([]*network.Cookie, error) {
+func (b *Browser) GetCookies(targetID string, timeout time.Duration) ([]*network.Cookie, error) {
 	var cookies []*network.Cookie
-	err := b.run(timeout, "get_cookies", "Retrieving cookies", func(ctx context.Context) (string, error) {
+	err := b.run(targetID, timeout, "get_cookies", "Retrieving cookies", func(ctx context.Context) (string, error) {
 		response, err := storage.GetCookies().Do(ctx)
 		if err != nil {
 			return "", err
@@ -603,8 +703,8 @@ func (b *Browser) GetCookies(timeout time.Duration) ([]*network.Cookie, error) {
 }
 
 // SetStorage populates localStorage/sessionStorage.
-func (b *Browser) SetStorage(timeout time.Duration, payload StoragePayload) error {
-	return b.run(timeout, "set_storage", "Applying storage values", func(ctx context.Context) (string, error) {
+func (b *Browser) SetStorage(targetID string, timeout time.Duration, payload StoragePayload) error {
+	return b.run(targetID, timeout, "set_storage", "Applying storage values", func(ctx context.Context) (string, error) {
 		tasks := chromedp.Tasks{}
 		for k, v := range payload.Local {
 			expr := fmt.Sprintf("window.localStorage.setItem(%s, %s);", jsString(k), jsString(v))
@@ -625,12 +725,12 @@ func (b *Browser) SetStorage(timeout time.Duration, payload StoragePayload) erro
 }
 
 // GetStorage extracts localStorage/sessionStorage content.
-func (b *Browser) GetStorage(timeout time.Duration) (StoragePayload, error) {
+func (b *Browser) GetStorage(targetID string, timeout time.Duration) (StoragePayload, error) {
 	result := StoragePayload{
 		Local:   map[string]string{},
 		Session: map[string]string{},
 	}
-	err := b.run(timeout, "get_storage", "Extracting storage", func(ctx context.Context) (string, error) {
+	err := b.run(targetID, timeout, "get_storage", "Extracting storage", func(ctx context.Context) (string, error) {
 		var localJSON, sessionJSON string
 		tasks := chromedp.Tasks{
 			chromedp.Evaluate(`JSON.stringify(Object.fromEntries(Object.keys(localStorage).map(k => [k, localStorage.getItem(k)])))`, &localJSON),
@@ -655,7 +755,7 @@ func (b *Browser) GetStorage(timeout time.Duration) (StoragePayload, error) {
 }
 
 // Click dispatches a mouse click event to a specific element.
-func (b *Browser) Click(timeout time.Duration, selector, button string) error {
+func (b *Browser) Click(targetID string, timeout time.Duration, selector, button string) error {
 	if selector == "" {
 		return errors.New("selector required")
 	}
@@ -668,7 +768,7 @@ func (b *Browser) Click(timeout time.Duration, selector, button string) error {
 		return fmt.Errorf("unsupported button %q", button)
 	}
 
-	return b.run(timeout, "click", fmt.Sprintf("Clicking %s (%s button)", selector, btn), func(ctx context.Context) (string, error) {
+	return b.run(targetID, timeout, "click", fmt.Sprintf("Clicking %s (%s button)", selector, btn), func(ctx context.Context) (string, error) {
 		actions := chromedp.Tasks{chromedp.WaitVisible(selector, chromedp.ByQuery)}
 		if btn == "left" {
 			actions = append(actions, chromedp.Click(selector, chromedp.ByQuery))
@@ -702,11 +802,11 @@ func (b *Browser) Click(timeout time.Duration, selector, button string) error {
 }
 
 // Type writes text into a specific element.
-func (b *Browser) Type(timeout time.Duration, selector, value string, clear bool) error {
+func (b *Browser) Type(targetID string, timeout time.Duration, selector, value string, clear bool) error {
 	if selector == "" {
 		return errors.New("selector required")
 	}
-	return b.run(timeout, "type", fmt.Sprintf("Typing into %s", selector), func(ctx context.Context) (string, error) {
+	return b.run(targetID, timeout, "type", fmt.Sprintf("Typing into %s", selector), func(ctx context.Context) (string, error) {
 		tasks := chromedp.Tasks{
 			chromedp.WaitVisible(selector, chromedp.ByQuery),
 			chromedp.Focus(selector, chromedp.ByQuery),
@@ -723,12 +823,12 @@ func (b *Browser) Type(timeout time.Duration, selector, value string, clear bool
 }
 
 // GetText retrieves the text content of a specific element.
-func (b *Browser) GetText(timeout time.Duration, selector string, visible bool) (string, error) {
+func (b *Browser) GetText(targetID string, timeout time.Duration, selector string, visible bool) (string, error) {
 	if selector == "" {
 		return "", errors.New("selector required")
 	}
 	var text string
-	err := b.run(timeout, "get_text", fmt.Sprintf("Reading text from %s", selector), func(ctx context.Context) (string, error) {
+	err := b.run(targetID, timeout, "get_text", fmt.Sprintf("Reading text from %s", selector), func(ctx context.Context) (string, error) {
 		action := chromedp.Text(selector, &text, chromedp.ByQuery)
 		if visible {
 			action = chromedp.Text(selector, &text, chromedp.ByQuery, chromedp.NodeVisible)
@@ -742,12 +842,12 @@ func (b *Browser) GetText(timeout time.Duration, selector string, visible bool)
 }
 
 // GetHTML retrieves the HTML content of a specific element.
-func (b *Browser) GetHTML(timeout time.Duration, selector string) (string, error) {
+func (b *Browser) GetHTML(targetID string, timeout time.Duration, selector string) (string, error) {
 	if selector == "" {
 		return "", errors.New("selector required")
 	}
 	var html string
-	err := b.run(timeout, "get_html", fmt.Sprintf("Retrieving HTML from %s", selector), func(ctx context.Context) (string, error) {
+	err := b.run(targetID, timeout, "get_html", fmt.Sprintf("Retrieving HTML from %s", selector), func(ctx context.Context) (string, error) {
 		if err := chromedp.Run(ctx, chromedp.InnerHTML(selector, &html, chromedp.ByQuery)); err != nil {
 			return "", err
 		}
@@ -757,13 +857,13 @@ func (b *Browser) GetHTML(timeout time.Duration, selector string) (string, error
 }
 
 // GetAttribute retrieves the value of a specific attribute from an element.
-func (b *Browser) GetAttribute(timeout time.Duration, selector, name string) (string, bool, error) {
+func (b *Browser) GetAttribute(targetID string, timeout time.Duration, selector, name string) (string, bool, error) {
 	if selector == "" || name == "" {
 		return "", false, errors.New("selector and attribute name required")
 	}
 	var value string
 	var ok bool
-	err := b.run(timeout, "get_attribute", fmt.Sprintf("Reading attribute %s from %s", name, selector), func(ctx context.Context) (string, error) {
+	err := b.run(targetID, timeout, "get_attribute", fmt.Sprintf("Reading attribute %s from %s", name, selector), func(ctx context.Context) (string, error) {
 		if err := chromedp.Run(ctx, chromedp.AttributeValue(selector, name, &value, &ok, chromedp.ByQuery)); err != nil {
 			return "", err
 		}
@@ -776,7 +876,7 @@ func (b *Browser) GetAttribute(timeout time.Duration, selector, name string) (st
 }
 
 // WaitForSelector waits for a specific element to become visible or ready.
-func (b *Browser) WaitForSelector(timeout time.Duration, selector string, visible bool) error {
+func (b *Browser) WaitForSelector(targetID string, timeout time.Duration, selector string, visible bool) error {
 	if selector == "" {
 		return errors.New("selector required")
 	}
@@ -784,7 +884,7 @@ func (b *Browser) WaitForSelector(timeout time.Duration, selector string, visibl
 	if visible {
 		actionLabel = "visible"
 	}
-	return b.run(timeout, "wait_for_selector", fmt.Sprintf("Waiting for %s to become %s", selector, actionLabel), func(ctx context.Context) (string, error) {
+	return b.run(targetID, timeout, "wait_for_selector", fmt.Sprintf("Waiting for %s to become %s", selector, actionLabel), func(ctx context.Context) (string, error) {
 		var err error
 		if visible {
 			err = chromedp.Run(ctx, chromedp.WaitVisible(selector, chromedp.ByQuery))
@@ -806,20 +906,29 @@ func (b *Browser) timeout(d time.Duration) time.Duration {
 	return d
 }
 
-func (b *Browser) run(timeout time.Duration, name, startLine string, fn func(ctx context.Context) (string, error)) error {
+func (b *Browser) run(targetID string, timeout time.Duration, name, startLine string, fn func(ctx context.Context) (string, error)) error {
 	timeout = b.timeout(timeout)
+	t, err := b.tab(targetID)
+	if err != nil {
+		b.publish("agent", fmt.Sprintf("%s failed: %v", name, err))
+		return err
+	}
 	if strings.TrimSpace(startLine) != "" {
 		b.publish("agent", startLine)
 	}
 	started := time.Now()
 
-	ctx, cancel := context.WithTimeout(b.ctx, timeout)
+	ctx, cancel := context.WithTimeout(t.ctx, timeout)
 	defer cancel()
 
-	b.mu.Lock()
-	defer b.mu.Unlock()
-
-	successMsg, err := fn(ctx)
+	// Actions on the same target run in submission order on its queue; the
+	// deadline covers both time spent waiting and time spent running.
+	var successMsg string
+	err = t.queue.submit(ctx, func(ctx context.Context) error {
+		msg, fnErr := fn(ctx)
+		successMsg = msg
+		return fnErr
+	})
 	if err != nil {
 		b.publish("agent", fmt.Sprintf("%s failed: %v", name, err))
 		return err
@@ -0,0 +1,189 @@
+package browser
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/security"
+	"github.com/chromedp/chromedp"
+)
+
+// CertificatePolicy decides how TLS certificate errors are treated.
+// IgnoreErrors is the default for new sessions, which may change it.
+// CABundle names a PEM file whose certificates are trusted browser-wide:
+// chains that fail validation but contain one of its keys are accepted.
+type CertificatePolicy struct {
+	IgnoreErrors bool   `json:"ignore_errors"`
+	CABundle     string `json:"ca_bundle,omitempty"`
+
+	spki []string
+}
+
+// CertificateInfo reports a session's certificate policy and how many
+// certificate errors it has overridden.
+type CertificateInfo struct {
+	IgnoreErrors bool   `json:"ignore_errors"`
+	CABundle     string `json:"ca_bundle,omitempty"`
+	TrustedKeys  int    `json:"trusted_keys"`
+	Overridden   int64  `json:"overridden"`
+}
+
+// normalize loads the CA bundle and hashes the public key of each of its
+// certificates.
+func (c CertificatePolicy) normalize() (CertificatePolicy, error) {
+	result := CertificatePolicy{IgnoreErrors: c.IgnoreErrors, CABundle: strings.TrimSpace(c.CABundle)}
+	if result.CABundle == "" {
+		return result, nil
+	}
+	data, err := os.ReadFile(result.CABundle)
+	if err != nil {
+		return CertificatePolicy{}, fmt.Errorf("browser: read ca bundle: %w", err)
+	}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return CertificatePolicy{}, fmt.Errorf("browser: parse ca bundle %s: %w", result.CABundle, err)
+		}
+		sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+		result.spki = append(result.spki, base64.StdEncoding.EncodeToString(sum[:]))
+	}
+	if len(result.spki) == 0 {
+		return CertificatePolicy{}, fmt.Errorf("browser: ca bundle %s holds no certificates", result.CABundle)
+	}
+	return result, nil
+}
+
+// flags returns the Chrome command-line switches trusting the CA bundle.
+// Chrome only honours them together with --user-data-dir, which the agent
+// always passes.
+func (c CertificatePolicy) flags() []string {
+	if len(c.spki) == 0 {
+		return nil
+	}
+	return []string{"--ignore-certificate-errors-spki-list=" + strings.Join(c.spki, ",")}
+}
+
+func (sp *sessionPolicy) setIgnoreCerts(ignore bool) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.ignoreCerts = ignore
+}
+
+func (sp *sessionPolicy) ignoresCerts() bool {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return sp.ignoreCerts
+}
+
+// certOverridden counts an overridden certificate error and reports whether
+// it is the first one seen for origin.
+func (sp *sessionPolicy) certOverridden(origin string) bool {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.certOverrides++
+	if sp.certOrigins == nil {
+		sp.certOrigins = make(map[string]bool)
+	}
+	if sp.certOrigins[origin] {
+		return false
+	}
+	sp.certOrigins[origin] = true
+	return true
+}
+
+func (sp *sessionPolicy) certificateInfo(global CertificatePolicy) CertificateInfo {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return CertificateInfo{
+		IgnoreErrors: sp.ignoreCerts,
+		CABundle:     global.CABundle,
+		TrustedKeys:  len(global.spki),
+		Overridden:   sp.certOverrides,
+	}
+}
+
+// watchCertificates records certificate errors the tab loads past. Chrome
+// reports a resource as insecure when its certificate failed validation, so
+// an insecure secure-scheme response is one whose error was ignored or
+// accepted through the CA bundle. Each origin is logged once per session;
+// every occurrence is counted.
+func (b *Browser) watchCertificates(t *tab) {
+	chromedp.ListenTarget(t.ctx, func(ev any) {
+		e, ok := ev.(*network.EventResponseReceived)
+		if !ok || e.Response == nil || e.Response.SecurityState != security.StateInsecure {
+			return
+		}
+		u, err := url.Parse(e.Response.URL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "wss") {
+			return
+		}
+		sp := b.policy(t.session)
+		if !sp.ignoresCerts() && len(b.cfg.Certificates.spki) == 0 {
+			return
+		}
+		origin := u.Scheme + "://" + u.Host
+		if !sp.certOverridden(origin) {
+			return
+		}
+		detail := ""
+		if d := e.Response.SecurityDetails; d != nil {
+			detail = fmt.Sprintf(" (subject %q, issuer %q)", d.SubjectName, d.Issuer)
+		}
+		b.publish("agent", fmt.Sprintf("target %s: ignored certificate error for %s%s", t.id, origin, detail))
+	})
+}
+
+// applyCertificates sets whether the tab ignores certificate errors.
+func applyCertificates(ctx context.Context, ignore bool) error {
+	return chromedp.Run(ctx, security.SetIgnoreCertificateErrors(ignore))
+}
+
+// Certificates reports the session's certificate policy.
+func (b *Browser) Certificates(sessionID string) (CertificateInfo, error) {
+	if _, err := b.lookupSession(sessionID); err != nil {
+		return CertificateInfo{}, err
+	}
+	return b.policy(strings.TrimSpace(sessionID)).certificateInfo(b.cfg.Certificates), nil
+}
+
+// SetIgnoreCertificateErrors changes whether every tab of the session,
+// including tabs opened later, loads pages despite certificate errors.
+func (b *Browser) SetIgnoreCertificateErrors(sessionID string, timeout time.Duration, ignore bool) (CertificateInfo, error) {
+	if _, err := b.lookupSession(sessionID); err != nil {
+		return CertificateInfo{}, err
+	}
+	sessionID = strings.TrimSpace(sessionID)
+	sp := b.policy(sessionID)
+	sp.setIgnoreCerts(ignore)
+	for _, t := range b.sessionTabs(sessionID) {
+		err := b.run(string(t.id), timeout, "sync_certificates", "", func(ctx context.Context) (string, error) {
+			return "", applyCertificates(ctx, ignore)
+		})
+		if err != nil {
+			return CertificateInfo{}, err
+		}
+	}
+	if ignore {
+		b.publish("agent", "certificate errors are now ignored")
+	} else {
+		b.publish("agent", "certificate errors are enforced")
+	}
+	return sp.certificateInfo(b.cfg.Certificates), nil
+}
@@ -0,0 +1,121 @@
+package browser
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA returns a PEM encoded self-signed certificate and the SPKI hash
+// Chrome expects for it.
+func testCA(t *testing.T, name string) ([]byte, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestCertificatePolicyNormalize(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	first, firstSPKI := testCA(t, "first")
+	second, secondSPKI := testCA(t, "second")
+	key := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("not a real key")})
+
+	single := write("single.pem", first)
+	bundle := write("bundle.pem", append(append(append([]byte("# corporate roots\n"), first...), key...), second...))
+	keysOnly := write("keys.pem", key)
+	garbage := write("garbage.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: []byte("junk")}))
+	empty := write("empty.pem", nil)
+
+	tests := []struct {
+		name     string
+		in       CertificatePolicy
+		wantSPKI []string
+		wantErr  string
+	}{
+		{name: "no bundle", in: CertificatePolicy{IgnoreErrors: true}},
+		{name: "blank bundle", in: CertificatePolicy{CABundle: "  "}},
+		{name: "single certificate", in: CertificatePolicy{CABundle: single}, wantSPKI: []string{firstSPKI}},
+		{name: "path is trimmed", in: CertificatePolicy{CABundle: " " + single + " "}, wantSPKI: []string{firstSPKI}},
+		{name: "other blocks are skipped", in: CertificatePolicy{CABundle: bundle}, wantSPKI: []string{firstSPKI, secondSPKI}},
+		{name: "missing file", in: CertificatePolicy{CABundle: filepath.Join(dir, "missing.pem")}, wantErr: "read ca bundle"},
+		{name: "no certificates", in: CertificatePolicy{CABundle: keysOnly}, wantErr: "holds no certificates"},
+		{name: "empty file", in: CertificatePolicy{CABundle: empty}, wantErr: "holds no certificates"},
+		{name: "unparseable certificate", in: CertificatePolicy{CABundle: garbage}, wantErr: "parse ca bundle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.in.normalize()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("normalize() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalize() error = %v", err)
+			}
+			if got.IgnoreErrors != tt.in.IgnoreErrors || got.CABundle != strings.TrimSpace(tt.in.CABundle) {
+				t.Fatalf("normalize() = %+v from %+v", got, tt.in)
+			}
+			if !reflect.DeepEqual(got.spki, tt.wantSPKI) {
+				t.Fatalf("spki = %v, want %v", got.spki, tt.wantSPKI)
+			}
+		})
+	}
+}
+
+func TestCertificatePolicyFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		in   CertificatePolicy
+		want []string
+	}{
+		{"no keys", CertificatePolicy{IgnoreErrors: true, CABundle: "ca.pem"}, nil},
+		{"one key", CertificatePolicy{spki: []string{"a"}}, []string{"--ignore-certificate-errors-spki-list=a"}},
+		{"several keys", CertificatePolicy{spki: []string{"a", "b"}}, []string{"--ignore-certificate-errors-spki-list=a,b"}},
+	}
+	for _, tt := range tests {
+		if got := tt.in.flags(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: flags() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	downloadsDirName = "downloads"
+
+	// downloadProgressInterval spaces out the progress lines a download
+	// writes to the log.
+	downloadProgressInterval = time.Second
+)
+
+// Download states.
+const (
+	DownloadInProgress = "in_progress"
+	DownloadCompleted  = "completed"
+	DownloadCanceled   = "canceled"
+)
+
+var (
+	// ErrUnknownDownload is returned for a download ID the session has not seen.
+	ErrUnknownDownload = errors.New("browser: unknown download")
+	// ErrDownloadInProgress is returned when reading a download that has not
+	// finished.
+	ErrDownloadInProgress = errors.New("browser: download still in progress")
+)
+
+// Download is a file a session's page downloaded. Files are stored under
+// their ID in the session's download directory; Filename is the name the
+// server suggested.
+type Download struct {
+	ID            string     `json:"id"`
+	TargetID      string     `json:"target_id"`
+	URL           string     `json:"url"`
+	Filename      string     `json:"filename"`
+	State         string     `json:"state"`
+	ReceivedBytes int64      `json:"received_bytes"`
+	TotalBytes    int64      `json:"total_bytes"`
+	StartedAt     time.Time  `json:"started_at"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+
+	path     string
+	reported time.Time
+}
+
+// downloadDir is where the session's downloads are written.
+func (b *Browser) downloadDir(sessionID string) string {
+	name := sessionID
+	if name == "" {
+		name = "default"
+	}
+	return filepath.Join(b.cfg.UserDataDir, downloadsDirName, name)
+}
+
+// sessionContextID returns the browser context of a session without touching
+// its idle deadline; empty for the default context.
+func (b *Browser) sessionContextID(sessionID string) cdp.BrowserContextID {
+	b.sessionsMu.RLock()
+	defer b.sessionsMu.RUnlock()
+	if s, ok := b.sessions[sessionID]; ok {
+		return s.contextID
+	}
+	return ""
+}
+
+// applyDownloads makes the tab's browser context save downloads into the
+// session's directory and report their progress.
+func (b *Browser) applyDownloads(ctx context.Context, t *tab) error {
+	dir := b.downloadDir(t.session)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	behavior := browser.SetDownloadBehavior(browser.SetDownloadBehaviorBehaviorAllowAndName).
+		WithDownloadPath(dir).
+		WithEventsEnabled(true)
+	if contextID := b.sessionContextID(t.session); contextID != "" {
+		behavior = behavior.WithBrowserContextID(contextID)
+	}
+	return chromedp.Run(ctx, behavior)
+}
+
+// watchDownloads tracks the downloads reported to the tab. Every tab of a
+// context may see the same events, so they are keyed by download ID.
+func (b *Browser) watchDownloads(t *tab) {
+	chromedp.ListenTarget(t.ctx, func(ev any) {
+		switch e := ev.(type) {
+		case *browser.EventDownloadWillBegin:
+			sp := b.policy(t.session)
+			if !sp.beginDownload(&Download{
+				ID:        e.GUID,
+				TargetID:  string(t.id),
+				URL:       e.URL,
+				Filename:  e.SuggestedFilename,
+				State:     DownloadInProgress,
+				StartedAt: time.Now().UTC(),
+				path:      filepath.Join(b.downloadDir(t.session), e.GUID),
+			}) {
+				return
+			}
+			b.publish("agent", fmt.Sprintf("download %s started: %s (%s)", e.GUID, e.SuggestedFilename, truncateForLog(e.URL, 120)))
+		case *browser.EventDownloadProgress:
+			if line := b.policy(t.session).progressDownload(e); line != "" {
+				b.publish("agent", line)
+			}
+		}
+	})
+}
+
+// beginDownload records a new download, reporting false if it is known.
+func (sp *sessionPolicy) beginDownload(d *Download) bool {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if sp.downloads == nil {
+		sp.downloads = make(map[string]*Download)
+	}
+	if _, ok := sp.downloads[d.ID]; ok {
+		return false
+	}
+	sp.downloads[d.ID] = d
+	return true
+}
+
+// progressDownload applies a progress event and returns the log line it
+// warrants, if any.
+func (sp *sessionPolicy) progressDownload(e *browser.EventDownloadProgress) string {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	d, ok := sp.downloads[e.GUID]
+	if !ok || d.State != DownloadInProgress {
+		return ""
+	}
+	d.ReceivedBytes = int64(e.ReceivedBytes)
+	d.TotalBytes = int64(e.TotalBytes)
+	now := time.Now().UTC()
+	switch e.State {
+	case browser.DownloadProgressStateCompleted:
+		d.State = DownloadCompleted
+		d.FinishedAt = &now
+		if e.FilePath != "" {
+			d.path = e.FilePath
+		}
+		return fmt.Sprintf("download %s completed: %s (%d bytes)", d.ID, d.Filename, d.ReceivedBytes)
+	case browser.DownloadProgressStateCanceled:
+		d.State = DownloadCanceled
+		d.FinishedAt = &now
+		return fmt.Sprintf("download %s canceled: %s", d.ID, d.Filename)
+	}
+	if now.Sub(d.reported) < downloadProgressInterval {
+		return ""
+	}
+	d.reported = now
+	if d.TotalBytes > 0 {
+		return fmt.Sprintf("download %s: %d/%d bytes", d.ID, d.ReceivedBytes, d.TotalBytes)
+	}
+	return fmt.Sprintf("download %s: %d bytes", d.ID, d.ReceivedBytes)
+}
+
+func (sp *sessionPolicy) download(id string) (Download, error) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	d, ok := sp.downloads[id]
+	if !ok {
+		return Download{}, ErrUnknownDownload
+	}
+	return *d, nil
+}
+
+func (sp *sessionPolicy) listDownloads() []Download {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	list := make([]Download, 0, len(sp.downloads))
+	for _, d := range sp.downloads {
+		list = append(list, *d)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].StartedAt.Before(list[j].StartedAt)
+	})
+	return list
+}
+
+func (sp *sessionPolicy) forgetDownload(id string) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	delete(sp.downloads, id)
+}
+
+// Downloads lists the session's downloads, oldest first.
+func (b *Browser) Downloads(sessionID string) ([]Download, error) {
+	if _, err := b.lookupSession(sessionID); err != nil {
+		return nil, err
+	}
+	return b.policy(strings.TrimSpace(sessionID)).listDownloads(), nil
+}
+
+// OpenDownload opens a completed download for reading. The caller closes
+// the file.
+func (b *Browser) OpenDownload(sessionID, id string) (Download, *os.File, error) {
+	if _, err := b.lookupSession(sessionID); err != nil {
+		return Download{}, nil, err
+	}
+	d, err := b.policy(strings.TrimSpace(sessionID)).download(id)
+	if err != nil {
+		return Download{}, nil, err
+	}
+	switch d.State {
+	case DownloadInProgress:
+		return Download{}, nil, ErrDownloadInProgress
+	case DownloadCanceled:
+		return Download{}, nil, fmt.Errorf("browser: download %s was canceled", id)
+	}
+	file, err := os.Open(d.path)
+	if err != nil {
+		return Download{}, nil, fmt.Errorf("browser: open download: %w", err)
+	}
+	return d, file, nil
+}
+
+// DeleteDownload cancels the download if it is still running and removes it
+// and its file.
+func (b *Browser) DeleteDownload(sessionID string, timeout time.Duration, id string) error {
+	if _, err := b.lookupSession(sessionID); err != nil {
+		return err
+	}
+	sessionID = strings.TrimSpace(sessionID)
+	sp := b.policy(sessionID)
+	d, err := sp.download(id)
+	if err != nil {
+		return err
+	}
+	if d.State == DownloadInProgress {
+		ctx, cancel, err := b.browserScope(timeout)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		cancelDownload := browser.CancelDownload(id)
+		if contextID := b.sessionContextID(sessionID); contextID != "" {
+			cancelDownload = cancelDownload.WithBrowserContextID(contextID)
+		}
+		if err := cancelDownload.Do(ctx); err != nil {
+			return fmt.Errorf("browser: cancel download: %w", err)
+		}
+	}
+	sp.forgetDownload(id)
+	// Chrome removes the partial file of a canceled download itself.
+	if err := os.Remove(d.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("browser: remove download: %w", err)
+	}
+	b.publish("agent", fmt.Sprintf("download %s deleted", id))
+	return nil
+}
@@ -0,0 +1,85 @@
+package browser
+
+import (
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/browser"
+)
+
+func TestProgressDownload(t *testing.T) {
+	sp := newSessionPolicy()
+	if !sp.beginDownload(&Download{ID: "d1", Filename: "report.pdf", State: DownloadInProgress, path: "/tmp/d1"}) {
+		t.Fatal("beginDownload(d1) = false for a new download")
+	}
+	if sp.beginDownload(&Download{ID: "d1"}) {
+		t.Fatal("beginDownload(d1) = true for a known download")
+	}
+	sp.beginDownload(&Download{ID: "d2", Filename: "stream.bin", State: DownloadInProgress})
+	sp.beginDownload(&Download{ID: "d3", Filename: "huge.iso", State: DownloadInProgress})
+
+	progress := func(id string, state browser.DownloadProgressState, received, total float64) *browser.EventDownloadProgress {
+		return &browser.EventDownloadProgress{GUID: id, State: state, ReceivedBytes: received, TotalBytes: total}
+	}
+	tests := []struct {
+		name      string
+		event     *browser.EventDownloadProgress
+		want      string
+		wantState string
+	}{
+		{"unknown download", progress("nope", browser.DownloadProgressStateInProgress, 1, 2), "", ""},
+		{"first progress is reported", progress("d1", browser.DownloadProgressStateInProgress, 100, 1000), "download d1: 100/1000 bytes", DownloadInProgress},
+		{"progress is rate limited", progress("d1", browser.DownloadProgressStateInProgress, 200, 1000), "", DownloadInProgress},
+		{"completion is always reported", progress("d1", browser.DownloadProgressStateCompleted, 1000, 1000), "download d1 completed: report.pdf (1000 bytes)", DownloadCompleted},
+		{"events after completion are ignored", progress("d1", browser.DownloadProgressStateCanceled, 0, 1000), "", DownloadCompleted},
+		{"unknown total", progress("d2", browser.DownloadProgressStateInProgress, 512, 0), "download d2: 512 bytes", DownloadInProgress},
+		{"cancellation", progress("d3", browser.DownloadProgressStateCanceled, 64, 4096), "download d3 canceled: huge.iso", DownloadCanceled},
+	}
+	for _, tt := range tests {
+		if got := sp.progressDownload(tt.event); got != tt.want {
+			t.Errorf("%s: progressDownload() = %q, want %q", tt.name, got, tt.want)
+		}
+		if tt.wantState == "" {
+			continue
+		}
+		d, err := sp.download(tt.event.GUID)
+		if err != nil {
+			t.Fatalf("%s: download(%s): %v", tt.name, tt.event.GUID, err)
+		}
+		if d.State != tt.wantState {
+			t.Errorf("%s: state = %q, want %q", tt.name, d.State, tt.wantState)
+		}
+	}
+
+	d1, _ := sp.download("d1")
+	if d1.ReceivedBytes != 1000 || d1.TotalBytes != 1000 || d1.FinishedAt == nil || d1.path != "/tmp/d1" {
+		t.Errorf("completed download = %+v", d1)
+	}
+	d3, _ := sp.download("d3")
+	if d3.ReceivedBytes != 64 || d3.FinishedAt == nil {
+		t.Errorf("canceled download = %+v", d3)
+	}
+}
+
+func TestProgressDownloadReportsAfterInterval(t *testing.T) {
+	sp := newSessionPolicy()
+	sp.beginDownload(&Download{ID: "d1", State: DownloadInProgress})
+	event := &browser.EventDownloadProgress{GUID: "d1", State: browser.DownloadProgressStateInProgress, ReceivedBytes: 10}
+	if sp.progressDownload(event) == "" {
+		t.Fatal("first progress not reported")
+	}
+	sp.downloads["d1"].reported = time.Now().Add(-downloadProgressInterval)
+	event.ReceivedBytes = 20
+	if got := sp.progressDownload(event); got != "download d1: 20 bytes" {
+		t.Fatalf("progressDownload() after interval = %q", got)
+	}
+}
+
+func TestProgressDownloadCompletedPath(t *testing.T) {
+	sp := newSessionPolicy()
+	sp.beginDownload(&Download{ID: "d1", State: DownloadInProgress, path: "/downloads/d1"})
+	sp.progressDownload(&browser.EventDownloadProgress{GUID: "d1", State: browser.DownloadProgressStateCompleted, FilePath: "/downloads/d1.pdf"})
+	if d, _ := sp.download("d1"); d.path != "/downloads/d1.pdf" {
+		t.Fatalf("path = %q, want the reported file path", d.path)
+	}
+}
@@ -0,0 +1,280 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	cpruntime "github.com/chromedp/cdproto/runtime"
+	"github.com/chromedp/chromedp"
+)
+
+const defaultFetchMaxBodyBytes = 10 << 20
+
+// FetchRequest is an HTTP request issued by the page itself, so it carries
+// the page's cookies and origin. At most one of Body, Form and JSON may be
+// set. Body is sent as is, or decoded first when BodyBase64 is set; Form is
+// URL-encoded unless Multipart is set; JSON is marshalled with a JSON
+// content type.
+type FetchRequest struct {
+	URL          string
+	Method       string
+	Headers      map[string]string
+	Body         string
+	BodyBase64   bool
+	Form         map[string]string
+	Multipart    bool
+	JSON         any
+	Credentials  string
+	MaxBodyBytes int64
+}
+
+// FetchResponse is the page's view of the response. Bodies that are not
+// valid UTF-8 are base64 encoded; JSON holds the parsed body when the
+// response declares a JSON content type.
+type FetchResponse struct {
+	URL        string            `json:"url"`
+	Status     int               `json:"status"`
+	StatusText string            `json:"status_text"`
+	OK         bool              `json:"ok"`
+	Redirected bool              `json:"redirected"`
+	Headers    map[string]string `json:"headers"`
+	Body       string            `json:"body"`
+	BodyBase64 bool              `json:"body_base64"`
+	BodySize   int64             `json:"body_size"`
+	Truncated  bool              `json:"truncated"`
+	JSON       any               `json:"json,omitempty"`
+}
+
+// fetchSpec is the request as handed to the page script.
+type fetchSpec struct {
+	URL         string            `json:"url"`
+	Method      string            `json:"method"`
+	Headers     map[string]string `json:"headers"`
+	Credentials string            `json:"credentials"`
+	Kind        string            `json:"kind"`
+	Body        string            `json:"body,omitempty"`
+	Form        map[string]string `json:"form,omitempty"`
+	Max         int64             `json:"max"`
+}
+
+// fetchScript runs the request and reads at most spec.max bytes of the body.
+const fetchScript = `(async () => {
+	const spec = JSON.parse(%s);
+	const init = {method: spec.method, headers: spec.headers, credentials: spec.credentials};
+	switch (spec.kind) {
+	case "text":
+		init.body = spec.body;
+		break;
+	case "base64":
+		init.body = Uint8Array.from(atob(spec.body), c => c.charCodeAt(0));
+		break;
+	case "urlencoded":
+		init.body = new URLSearchParams(spec.form);
+		break;
+	case "multipart": {
+		const data = new FormData();
+		for (const [key, value] of Object.entries(spec.form)) data.append(key, value);
+		init.body = data;
+		break;
+	}
+	}
+	const response = await fetch(spec.url, init);
+	const headers = {};
+	response.headers.forEach((value, key) => { headers[key] = value; });
+
+	const chunks = [];
+	let size = 0;
+	let truncated = false;
+	if (response.body) {
+		const reader = response.body.getReader();
+		for (;;) {
+			const {done, value} = await reader.read();
+			if (done) break;
+			if (size + value.length > spec.max) {
+				chunks.push(value.subarray(0, spec.max - size));
+				size = spec.max;
+				truncated = true;
+				await reader.cancel();
+				break;
+			}
+			chunks.push(value);
+			size += value.length;
+		}
+	}
+	const bytes = new Uint8Array(size);
+	let offset = 0;
+	for (const chunk of chunks) { bytes.set(chunk, offset); offset += chunk.length; }
+
+	let body;
+	let base64 = false;
+	try {
+		body = new TextDecoder("utf-8", {fatal: true}).decode(bytes);
+	} catch (err) {
+		let binary = "";
+		for (let i = 0; i < bytes.length; i += 0x8000) {
+			binary += String.fromCharCode.apply(null, bytes.subarray(i, i + 0x8000));
+		}
+		body = btoa(binary);
+		base64 = true;
+	}
+	return {
+		url: response.url,
+		status: response.status,
+		status_text: response.statusText,
+		ok: response.ok,
+		redirected: response.redirected,
+		headers,
+		body,
+		body_base64: base64,
+		body_size: size,
+		truncated,
+	};
+})()`
+
+// spec validates the request and converts it for the page script.
+func (fr FetchRequest) spec() (fetchSpec, error) {
+	if strings.TrimSpace(fr.URL) == "" {
+		return fetchSpec{}, errors.New("browser: url is required")
+	}
+	spec := fetchSpec{
+		URL:         fr.URL,
+		Method:      strings.ToUpper(strings.TrimSpace(fr.Method)),
+		Headers:     make(map[string]string, len(fr.Headers)),
+		Credentials: strings.ToLower(strings.TrimSpace(fr.Credentials)),
+		Kind:        "none",
+		Max:         fr.MaxBodyBytes,
+	}
+	if spec.Method == "" {
+		spec.Method = http.MethodGet
+	}
+	switch spec.Credentials {
+	case "":
+		spec.Credentials = "include"
+	case "include", "same-origin", "omit":
+	default:
+		return fetchSpec{}, fmt.Errorf("browser: unknown credentials mode %q (want include, same-origin or omit)", fr.Credentials)
+	}
+	if spec.Max <= 0 {
+		spec.Max = defaultFetchMaxBodyBytes
+	}
+	for name, value := range fr.Headers {
+		spec.Headers[name] = value
+	}
+
+	bodies := 0
+	if fr.Body != "" {
+		bodies++
+		spec.Kind, spec.Body = "text", fr.Body
+		if fr.BodyBase64 {
+			if _, err := base64.StdEncoding.DecodeString(fr.Body); err != nil {
+				return fetchSpec{}, fmt.Errorf("browser: body is not valid base64: %w", err)
+			}
+			spec.Kind = "base64"
+		}
+	}
+	if fr.Form != nil {
+		bodies++
+		spec.Kind, spec.Form = "urlencoded", fr.Form
+		if fr.Multipart {
+			spec.Kind = "multipart"
+		}
+	}
+	if fr.JSON != nil {
+		bodies++
+		data, err := json.Marshal(fr.JSON)
+		if err != nil {
+			return fetchSpec{}, fmt.Errorf("browser: encode json body: %w", err)
+		}
+		spec.Kind, spec.Body = "text", string(data)
+		if !hasHeader(spec.Headers, "Content-Type") {
+			spec.Headers["Content-Type"] = "application/json"
+		}
+	}
+	switch {
+	case bodies > 1:
+		return fetchSpec{}, errors.New("browser: set only one of body, form and json")
+	case bodies == 1 && (spec.Method == http.MethodGet || spec.Method == http.MethodHead):
+		return fetchSpec{}, fmt.Errorf("browser: %s requests cannot have a body", spec.Method)
+	}
+	return spec, nil
+}
+
+func hasHeader(headers map[string]string, name string) bool {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fetch issues an HTTP request from the page with its cookies and returns the
+// response as the page sees it.
+func (b *Browser) Fetch(targetID string, timeout time.Duration, req FetchRequest) (FetchResponse, error) {
+	spec, err := req.spec()
+	if err != nil {
+		return FetchResponse{}, err
+	}
+	return b.fetch(targetID, timeout, "fetch", fmt.Sprintf("Fetching %s %s", spec.Method, spec.URL), spec)
+}
+
+func (b *Browser) fetch(targetID string, timeout time.Duration, name, startLine string, spec fetchSpec) (FetchResponse, error) {
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return FetchResponse{}, err
+	}
+	expression := fmt.Sprintf(fetchScript, jsString(string(specJSON)))
+
+	var response FetchResponse
+	err = b.run(targetID, timeout, name, truncateForLog(startLine, 120), func(ctx context.Context) (string, error) {
+		var remote *cpruntime.RemoteObject
+		err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			var exception *cpruntime.ExceptionDetails
+			var err error
+			remote, exception, err = cpruntime.Evaluate(expression).
+				WithReturnByValue(true).
+				WithAwaitPromise(true).
+				Do(ctx)
+			if err == nil && exception != nil {
+				err = exceptionError(exception)
+			}
+			return err
+		}))
+		if err != nil {
+			return "", fmt.Errorf("%s %s: %w", spec.Method, spec.URL, err)
+		}
+		if remote == nil || len(remote.Value) == 0 {
+			return "", fmt.Errorf("%s %s: empty result", spec.Method, spec.URL)
+		}
+		if err := json.Unmarshal(remote.Value, &response); err != nil {
+			return "", fmt.Errorf("%s %s: decode result: %w", spec.Method, spec.URL, err)
+		}
+		return fmt.Sprintf("%s %s returned %d", spec.Method, spec.URL, response.Status), nil
+	})
+	if err != nil {
+		return FetchResponse{}, err
+	}
+
+	if !response.BodyBase64 && !response.Truncated && strings.Contains(strings.ToLower(response.Headers["content-type"]), "json") {
+		var parsed any
+		if json.Unmarshal([]byte(response.Body), &parsed) == nil {
+			response.JSON = parsed
+		}
+	}
+	return response, nil
+}
+
+// exceptionError turns a script exception, such as a rejected fetch, into an
+// error.
+func exceptionError(details *cpruntime.ExceptionDetails) error {
+	if details.Exception != nil && details.Exception.Description != "" {
+		return errors.New(details.Exception.Description)
+	}
+	return errors.New(details.Text)
+}
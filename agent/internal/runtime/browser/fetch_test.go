@@ -0,0 +1,121 @@
+package browser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFetchRequestSpec(t *testing.T) {
+	const url = "https://example.com/api"
+	tests := []struct {
+		name    string
+		in      FetchRequest
+		want    fetchSpec
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			in:   FetchRequest{URL: url},
+			want: fetchSpec{URL: url, Method: "GET", Headers: map[string]string{}, Credentials: "include", Kind: "none", Max: defaultFetchMaxBodyBytes},
+		},
+		{
+			name: "method and credentials are normalized",
+			in:   FetchRequest{URL: url, Method: " delete ", Credentials: " Same-Origin ", MaxBodyBytes: 512},
+			want: fetchSpec{URL: url, Method: "DELETE", Headers: map[string]string{}, Credentials: "same-origin", Kind: "none", Max: 512},
+		},
+		{
+			name: "text body",
+			in:   FetchRequest{URL: url, Method: "POST", Body: "hello", Headers: map[string]string{"X-Test": "1"}},
+			want: fetchSpec{URL: url, Method: "POST", Headers: map[string]string{"X-Test": "1"}, Credentials: "include", Kind: "text", Body: "hello", Max: defaultFetchMaxBodyBytes},
+		},
+		{
+			name: "base64 body",
+			in:   FetchRequest{URL: url, Method: "PUT", Body: "AAEC", BodyBase64: true},
+			want: fetchSpec{URL: url, Method: "PUT", Headers: map[string]string{}, Credentials: "include", Kind: "base64", Body: "AAEC", Max: defaultFetchMaxBodyBytes},
+		},
+		{
+			name: "urlencoded form",
+			in:   FetchRequest{URL: url, Method: "POST", Form: map[string]string{"q": "go"}},
+			want: fetchSpec{URL: url, Method: "POST", Headers: map[string]string{}, Credentials: "include", Kind: "urlencoded", Form: map[string]string{"q": "go"}, Max: defaultFetchMaxBodyBytes},
+		},
+		{
+			name: "multipart form",
+			in:   FetchRequest{URL: url, Method: "POST", Form: map[string]string{"q": "go"}, Multipart: true},
+			want: fetchSpec{URL: url, Method: "POST", Headers: map[string]string{}, Credentials: "include", Kind: "multipart", Form: map[string]string{"q": "go"}, Max: defaultFetchMaxBodyBytes},
+		},
+		{
+			name: "json sets content type",
+			in:   FetchRequest{URL: url, Method: "POST", JSON: map[string]any{"a": 1}},
+			want: fetchSpec{URL: url, Method: "POST", Headers: map[string]string{"Content-Type": "application/json"}, Credentials: "include", Kind: "text", Body: `{"a":1}`, Max: defaultFetchMaxBodyBytes},
+		},
+		{
+			name: "json keeps caller content type",
+			in:   FetchRequest{URL: url, Method: "PATCH", JSON: []int{1}, Headers: map[string]string{"content-type": "application/merge-patch+json"}},
+			want: fetchSpec{URL: url, Method: "PATCH", Headers: map[string]string{"content-type": "application/merge-patch+json"}, Credentials: "include", Kind: "text", Body: "[1]", Max: defaultFetchMaxBodyBytes},
+		},
+		{
+			name:    "url required",
+			in:      FetchRequest{URL: "  "},
+			wantErr: "url is required",
+		},
+		{
+			name:    "unknown credentials",
+			in:      FetchRequest{URL: url, Credentials: "always"},
+			wantErr: `unknown credentials mode "always"`,
+		},
+		{
+			name:    "invalid base64",
+			in:      FetchRequest{URL: url, Method: "POST", Body: "not base64!", BodyBase64: true},
+			wantErr: "body is not valid base64",
+		},
+		{
+			name:    "unencodable json",
+			in:      FetchRequest{URL: url, Method: "POST", JSON: func() {}},
+			wantErr: "encode json body",
+		},
+		{
+			name:    "two bodies",
+			in:      FetchRequest{URL: url, Method: "POST", Body: "x", JSON: 1},
+			wantErr: "set only one of body, form and json",
+		},
+		{
+			name:    "body on get",
+			in:      FetchRequest{URL: url, Body: "x"},
+			wantErr: "GET requests cannot have a body",
+		},
+		{
+			name:    "form on head",
+			in:      FetchRequest{URL: url, Method: "head", Form: map[string]string{}},
+			wantErr: "HEAD requests cannot have a body",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.in.spec()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("spec() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("spec() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("spec() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchRequestSpecCopiesHeaders(t *testing.T) {
+	headers := map[string]string{"Accept": "text/plain"}
+	spec, err := FetchRequest{URL: "https://example.com/", Method: "POST", Headers: headers, JSON: true}.spec()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := headers["Content-Type"]; ok || spec.Headers["Content-Type"] == "" {
+		t.Fatalf("caller headers = %v, spec headers = %v", headers, spec.Headers)
+	}
+}
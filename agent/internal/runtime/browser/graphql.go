@@ -0,0 +1,148 @@
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GraphQLOperation is one GraphQL request as sent on the wire. Query may be
+// omitted when Extensions carries an Apollo persistedQuery hash.
+type GraphQLOperation struct {
+	Query         string         `json:"query,omitempty"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables"`
+	Extensions    map[string]any `json:"extensions,omitempty"`
+}
+
+// GraphQLRequest posts one operation, or with Batch set all Operations as a
+// single array body.
+type GraphQLRequest struct {
+	Endpoint   string
+	Operations []GraphQLOperation
+	Batch      bool
+	Headers    map[string]string
+}
+
+func (gr GraphQLRequest) validate() error {
+	if strings.TrimSpace(gr.Endpoint) == "" {
+		return errors.New("browser: endpoint is required")
+	}
+	switch {
+	case len(gr.Operations) == 0 && gr.Batch:
+		return errors.New("browser: batch has no operations")
+	case len(gr.Operations) == 0:
+		return errors.New("browser: no operation to send")
+	case !gr.Batch && len(gr.Operations) > 1:
+		return fmt.Errorf("browser: %d operations can only be sent as a batch", len(gr.Operations))
+	}
+	for i, op := range gr.Operations {
+		if strings.TrimSpace(op.Query) != "" {
+			continue
+		}
+		if _, persisted := op.Extensions["persistedQuery"]; persisted {
+			continue
+		}
+		if gr.Batch {
+			return fmt.Errorf("browser: operation %d: query or extensions.persistedQuery is required", i)
+		}
+		return errors.New("browser: query or extensions.persistedQuery is required")
+	}
+	return nil
+}
+
+// GraphQLResponse is the outcome of a GraphQL POST. Data is set for a single
+// operation and Results for a batch; Errors collects the GraphQL errors of
+// every result.
+type GraphQLResponse struct {
+	Status  int               `json:"status"`
+	OK      bool              `json:"ok"`
+	Text    string            `json:"text"`
+	JSON    any               `json:"json"`
+	Headers map[string]string `json:"headers"`
+	Data    any               `json:"data,omitempty"`
+	Results []any             `json:"results,omitempty"`
+	Errors  []any             `json:"errors"`
+}
+
+// GraphQL executes a GraphQL POST using the browser context, preserving
+// session data. Transport status is reported as status/ok; GraphQL errors
+// from the response body are collected under errors, tagged with their
+// operation index for batches.
+func (b *Browser) GraphQL(targetID string, timeout time.Duration, req GraphQLRequest) (*GraphQLResponse, error) {
+	if err := req.validate(); err != nil {
+		return nil, err
+	}
+	for i := range req.Operations {
+		if req.Operations[i].Variables == nil {
+			req.Operations[i].Variables = map[string]any{}
+		}
+	}
+
+	var body any = req.Operations[0]
+	line := fmt.Sprintf("GraphQL POST %s", req.Endpoint)
+	if name := req.Operations[0].OperationName; name != "" {
+		line = fmt.Sprintf("GraphQL POST %s (%s)", req.Endpoint, name)
+	}
+	if req.Batch {
+		body = req.Operations
+		line = fmt.Sprintf("GraphQL POST %s (batch of %d)", req.Endpoint, len(req.Operations))
+	}
+	spec, err := FetchRequest{
+		URL:     req.Endpoint,
+		Method:  http.MethodPost,
+		Headers: req.Headers,
+		JSON:    body,
+	}.spec()
+	if err != nil {
+		return nil, err
+	}
+	result, err := b.fetch(targetID, timeout, "graphql", line, spec)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &GraphQLResponse{
+		Status:  result.Status,
+		OK:      result.OK,
+		Text:    result.Body,
+		JSON:    result.JSON,
+		Headers: result.Headers,
+		Errors:  []any{},
+	}
+	if req.Batch {
+		results, _ := result.JSON.([]any)
+		for i, item := range results {
+			for _, e := range graphQLResultErrors(item) {
+				if tagged, ok := e.(map[string]any); ok {
+					copied := make(map[string]any, len(tagged)+1)
+					for key, value := range tagged {
+						copied[key] = value
+					}
+					copied["operation_index"] = i
+					e = copied
+				}
+				response.Errors = append(response.Errors, e)
+			}
+		}
+		response.Results = results
+	} else {
+		response.Errors = append(response.Errors, graphQLResultErrors(result.JSON)...)
+		if object, ok := result.JSON.(map[string]any); ok {
+			response.Data = object["data"]
+		}
+	}
+	return response, nil
+}
+
+// graphQLResultErrors returns the errors array of one GraphQL result.
+func graphQLResultErrors(result any) []any {
+	object, ok := result.(map[string]any)
+	if !ok {
+		return nil
+	}
+	list, _ := object["errors"].([]any)
+	return list
+}
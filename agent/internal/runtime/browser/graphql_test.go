@@ -0,0 +1,60 @@
+package browser
+
+import "testing"
+
+func TestGraphQLRequestValidate(t *testing.T) {
+	const endpoint = "https://example.com/graphql"
+	query := GraphQLOperation{Query: "{ viewer { id } }"}
+	persisted := GraphQLOperation{Extensions: map[string]any{
+		"persistedQuery": map[string]any{"version": 1, "sha256Hash": "abc123"},
+	}}
+	empty := GraphQLOperation{OperationName: "Viewer"}
+
+	tests := []struct {
+		name    string
+		req     GraphQLRequest
+		wantErr string
+	}{
+		{"single query", GraphQLRequest{Endpoint: endpoint, Operations: []GraphQLOperation{query}}, ""},
+		{"persisted query without text", GraphQLRequest{Endpoint: endpoint, Operations: []GraphQLOperation{persisted}}, ""},
+		{"batch", GraphQLRequest{Endpoint: endpoint, Batch: true, Operations: []GraphQLOperation{query, persisted}}, ""},
+		{"batch of one", GraphQLRequest{Endpoint: endpoint, Batch: true, Operations: []GraphQLOperation{query}}, ""},
+		{"no endpoint", GraphQLRequest{Endpoint: " ", Operations: []GraphQLOperation{query}}, "browser: endpoint is required"},
+		{"no operation", GraphQLRequest{Endpoint: endpoint}, "browser: no operation to send"},
+		{"empty batch", GraphQLRequest{Endpoint: endpoint, Batch: true}, "browser: batch has no operations"},
+		{"several without batch", GraphQLRequest{Endpoint: endpoint, Operations: []GraphQLOperation{query, query}}, "browser: 2 operations can only be sent as a batch"},
+		{"no query", GraphQLRequest{Endpoint: endpoint, Operations: []GraphQLOperation{empty}}, "browser: query or extensions.persistedQuery is required"},
+		{"blank query", GraphQLRequest{Endpoint: endpoint, Operations: []GraphQLOperation{{Query: "  \n"}}}, "browser: query or extensions.persistedQuery is required"},
+		{"batch entry without query", GraphQLRequest{Endpoint: endpoint, Batch: true, Operations: []GraphQLOperation{query, persisted, empty}}, "browser: operation 2: query or extensions.persistedQuery is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("validate() = %v, want nil", err)
+			case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+				t.Fatalf("validate() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGraphQLResultErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		result any
+		want   int
+	}{
+		{"no errors", map[string]any{"data": map[string]any{}}, 0},
+		{"errors", map[string]any{"errors": []any{map[string]any{"message": "a"}, map[string]any{"message": "b"}}}, 2},
+		{"malformed errors", map[string]any{"errors": "boom"}, 0},
+		{"not an object", []any{}, 0},
+		{"nil", nil, 0},
+	}
+	for _, tt := range tests {
+		if got := graphQLResultErrors(tt.result); len(got) != tt.want {
+			t.Errorf("%s: graphQLResultErrors() = %v, want %d errors", tt.name, got, tt.want)
+		}
+	}
+}
@@ -1,10 +1,16 @@
 package browser
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -14,376 +20,1137 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
-func (r *Runtime) mountBrowserRoutes(router chi.Router) {
-	router.Post("/navigate", func(w http.ResponseWriter, req *http.Request) {
-		var payload navigateRequest
-		if err := decodeRequest(req, &payload); err != nil {
+func (r *Runtime) handleNavigate(w http.ResponseWriter, req *http.Request) {
+	var payload navigateRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(payload.URL) == "" {
+		errorJSON(w, http.StatusBadRequest, errors.New("url is required"))
+		return
+	}
+	if _, err := parseWaitUntil(payload.WaitUntil); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	if payload.Block != nil {
+		if _, err := payload.Block.normalize(); err != nil {
 			errorJSON(w, http.StatusBadRequest, err)
 			return
 		}
-		if strings.TrimSpace(payload.URL) == "" {
-			errorJSON(w, http.StatusBadRequest, errors.New("url is required"))
-			return
-		}
-		if err := r.real.Navigate(r.duration(payload.TimeoutMs), payload.URL); err != nil {
-			errorJSON(w, http.StatusInternalServerError, err)
-			return
-		}
-		okJSON(w)
+	}
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	opts := NavigateOptions{
+		Headers:      payload.Headers,
+		Credentials:  payload.Credentials,
+		Block:        payload.Block,
+		WaitUntil:    payload.WaitUntil,
+		FailOnStatus: payload.FailOnStatus,
+	}
+	result, err := r.real.Navigate(targetID, r.duration(req, payload.TimeoutMs), payload.URL, opts)
+	if errors.Is(err, ErrHTTPStatus) {
+		errorJSON(w, http.StatusBadGateway, err)
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, navigateResponse{Status: "ok", Navigation: result})
+}
+
+func (r *Runtime) handleReload(w http.ResponseWriter, req *http.Request) {
+	var payload reloadRequest
+	_ = decodeRequest(req, &payload)
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	if _, err := parseWaitUntil(payload.WaitUntil); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	result, err := r.real.Reload(targetID, r.duration(req, payload.TimeoutMs), payload.IgnoreCache, payload.WaitUntil)
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, navigateResponse{Status: "ok", Navigation: result})
+}
+
+func (r *Runtime) handleBack(w http.ResponseWriter, req *http.Request) {
+	targetID, ok := r.resolveTarget(w, req, queryTarget(req))
+	if !ok {
+		return
+	}
+	waitUntil := req.URL.Query().Get("wait_until")
+	if _, err := parseWaitUntil(waitUntil); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	result, err := r.real.Back(targetID, r.duration(req, queryTimeout(req)), waitUntil)
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, navigateResponse{Status: "ok", Navigation: result})
+}
+
+func (r *Runtime) handleForward(w http.ResponseWriter, req *http.Request) {
+	targetID, ok := r.resolveTarget(w, req, queryTarget(req))
+	if !ok {
+		return
+	}
+	waitUntil := req.URL.Query().Get("wait_until")
+	if _, err := parseWaitUntil(waitUntil); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	result, err := r.real.Forward(targetID, r.duration(req, queryTimeout(req)), waitUntil)
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, navigateResponse{Status: "ok", Navigation: result})
+}
+
+func (r *Runtime) handleViewport(w http.ResponseWriter, req *http.Request) {
+	var payload viewportRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	if err := r.real.SetViewport(targetID, r.duration(req, payload.TimeoutMs), payload.Width, payload.Height, payload.Scale, payload.Mobile); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	okJSON(w)
+}
+
+func (r *Runtime) handleUserAgent(w http.ResponseWriter, req *http.Request) {
+	var payload userAgentRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	if err := r.real.SetUserAgent(targetID, r.duration(req, payload.TimeoutMs), payload.UserAgent, payload.AcceptLanguage, payload.Platform); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	okJSON(w)
+}
+
+func (r *Runtime) handleWaitNavigation(w http.ResponseWriter, req *http.Request) {
+	var payload waitNavigationRequest
+	_ = decodeRequest(req, &payload)
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	if _, err := parseWaitUntil(payload.WaitUntil); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	result, err := r.real.WaitForNavigation(targetID, r.duration(req, payload.TimeoutMs), payload.WaitUntil)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, navigateResponse{Status: "ok", Navigation: result})
+}
+
+func (r *Runtime) handleScreenshot(w http.ResponseWriter, req *http.Request) {
+	var payload screenshotRequest
+	_ = decodeRequest(req, &payload)
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	data, err := r.real.Screenshot(targetID, r.duration(req, payload.TimeoutMs), payload.FullPage, payload.Format, payload.Quality)
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, screenshotResponse{
+		Data:       base64.StdEncoding.EncodeToString(data),
+		Format:     strings.ToLower(payload.Format),
+		FullPage:   payload.FullPage,
+		ByteLength: len(data),
+		CapturedAt: time.Now().UTC().Format(time.RFC3339Nano),
 	})
+}
 
-	router.Method(http.MethodPost, "/reload", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		var payload reloadRequest
-		_ = decodeRequest(req, &payload)
-		if err := r.real.Reload(r.duration(payload.TimeoutMs), payload.IgnoreCache); err != nil {
-			errorJSON(w, http.StatusInternalServerError, err)
-			return
-		}
-		okJSON(w)
-	}))
+func (r *Runtime) handleQueueStats(w http.ResponseWriter, req *http.Request) {
+	respondJSON(w, http.StatusOK, r.real.QueueStats())
+}
 
-	router.Method(http.MethodPost, "/back", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		if err := r.real.Back(r.duration(queryTimeout(req))); err != nil {
-			errorJSON(w, http.StatusInternalServerError, err)
-			return
-		}
-		okJSON(w)
-	}))
+func (r *Runtime) handleListTabs(w http.ResponseWriter, req *http.Request) {
+	tabs, err := r.real.Tabs(sessionID(req), r.duration(req, queryTimeout(req)))
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, tabsResponse{Tabs: tabs})
+}
 
-	router.Method(http.MethodPost, "/forward", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		if err := r.real.Forward(r.duration(queryTimeout(req))); err != nil {
-			errorJSON(w, http.StatusInternalServerError, err)
-			return
-		}
-		okJSON(w)
-	}))
+func (r *Runtime) handleOpenTab(w http.ResponseWriter, req *http.Request) {
+	var payload newTabRequest
+	_ = decodeRequest(req, &payload)
+	info, err := r.real.NewTab(sessionID(req), r.duration(req, payload.TimeoutMs), payload.URL, payload.Activate)
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, info)
+}
 
-	router.Method(http.MethodPost, "/viewport", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		var payload viewportRequest
-		if err := decodeRequest(req, &payload); err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
-		}
-		if err := r.real.SetViewport(r.duration(payload.TimeoutMs), payload.Width, payload.Height, payload.Scale, payload.Mobile); err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
-		}
-		okJSON(w)
-	}))
+func (r *Runtime) handleActivateTab(w http.ResponseWriter, req *http.Request) {
+	if err := r.real.ActivateTab(sessionID(req), r.duration(req, queryTimeout(req)), chi.URLParam(req, "targetID")); err != nil {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	okJSON(w)
+}
 
-	router.Method(http.MethodPost, "/user-agent", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		var payload userAgentRequest
-		if err := decodeRequest(req, &payload); err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
-		}
-		if err := r.real.SetUserAgent(r.duration(payload.TimeoutMs), payload.UserAgent, payload.AcceptLanguage, payload.Platform); err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
-		}
-		okJSON(w)
-	}))
+func (r *Runtime) handleCloseTab(w http.ResponseWriter, req *http.Request) {
+	if err := r.real.CloseTab(sessionID(req), r.duration(req, queryTimeout(req)), chi.URLParam(req, "targetID")); err != nil {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	okJSON(w)
+}
 
-	router.Method(http.MethodPost, "/wait-navigation", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		var payload waitNavigationRequest
-		_ = decodeRequest(req, &payload)
-		if err := r.real.WaitForNavigation(r.duration(payload.TimeoutMs)); err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
-		}
-		okJSON(w)
-	}))
+func (r *Runtime) handleListSessions(w http.ResponseWriter, req *http.Request) {
+	respondJSON(w, http.StatusOK, sessionsResponse{Sessions: r.real.Sessions()})
+}
 
-	router.Post("/screenshot", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		var payload screenshotRequest
-		_ = decodeRequest(req, &payload)
-		data, err := r.real.Screenshot(r.duration(payload.TimeoutMs), payload.FullPage, payload.Format, payload.Quality)
-		if err != nil {
-			errorJSON(w, http.StatusInternalServerError, err)
-			return
-		}
-		respondJSON(w, http.StatusOK, map[string]any{
-			"data":        base64.StdEncoding.EncodeToString(data),
-			"format":      strings.ToLower(payload.Format),
-			"full_page":   payload.FullPage,
-			"byte_length": len(data),
-			"captured_at": time.Now().UTC().Format(time.RFC3339Nano),
-		})
-	}))
+func (r *Runtime) handleCreateSession(w http.ResponseWriter, req *http.Request) {
+	var payload createSessionRequest
+	// An empty body creates a session with the default settings.
+	if err := decodeRequest(req, &payload); err != nil && !errors.Is(err, io.EOF) {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	opts, err := SessionOptions{
+		IdleTimeout:      time.Duration(payload.IdleTimeoutMs) * time.Millisecond,
+		Block:            payload.Block,
+		Headers:          payload.Headers,
+		Credentials:      payload.Credentials,
+		Throttling:       payload.Throttling,
+		Proxy:            payload.Proxy,
+		IgnoreCertErrors: payload.IgnoreCertErrors,
+	}.normalize()
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	info, err := r.real.CreateSession(r.duration(req, payload.TimeoutMs), opts)
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set(SessionHeader, info.ID)
+	respondJSON(w, http.StatusOK, info)
 }
 
-func (r *Runtime) mountDOMRoutes(router chi.Router) {
-	router.Post("/click", func(w http.ResponseWriter, req *http.Request) {
-		var payload clickRequest
-		if err := decodeRequest(req, &payload); err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
+func (r *Runtime) handleDeleteSession(w http.ResponseWriter, req *http.Request) {
+	err := r.real.DeleteSession(r.duration(req, queryTimeout(req)), chi.URLParam(req, "sessionID"))
+	if errors.Is(err, ErrUnknownSession) {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	okJSON(w)
+}
+
+func (r *Runtime) handleClick(w http.ResponseWriter, req *http.Request) {
+	var payload clickRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	if err := r.real.Click(targetID, r.duration(req, payload.TimeoutMs), payload.Selector, payload.Button); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	okJSON(w)
+}
+
+func (r *Runtime) handleType(w http.ResponseWriter, req *http.Request) {
+	var payload typeRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	if err := r.real.Type(targetID, r.duration(req, payload.TimeoutMs), payload.Selector, payload.Value, payload.Clear); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	okJSON(w)
+}
+
+func (r *Runtime) handleUpload(w http.ResponseWriter, req *http.Request) {
+	limit := r.maxUpload
+	if limit <= 0 {
+		limit = DefaultMaxUploadBytes
+	}
+	req.Body = http.MaxBytesReader(w, req.Body, limit)
+
+	var payload uploadRequest
+	var files []UploadFile
+	if mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		if err := req.ParseMultipartForm(uploadMemoryBytes); err != nil {
+			errorJSON(w, uploadErrorStatus(err), err)
 			return
 		}
-		if err := r.real.Click(r.duration(payload.TimeoutMs), payload.Selector, payload.Button); err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
+		defer req.MultipartForm.RemoveAll()
+		payload.TargetID = req.FormValue("target_id")
+		payload.Selector = req.FormValue("selector")
+		if value := req.FormValue("timeout_ms"); value != "" {
+			ms, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				errorJSON(w, http.StatusBadRequest, fmt.Errorf("invalid timeout_ms %q", value))
+				return
+			}
+			payload.TimeoutMs = ms
 		}
-		okJSON(w)
-	})
-
-	router.Post("/type", func(w http.ResponseWriter, req *http.Request) {
-		var payload typeRequest
-		if err := decodeRequest(req, &payload); err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
+		for field := range req.MultipartForm.File {
+			if field != uploadField {
+				errorJSON(w, http.StatusBadRequest, fmt.Errorf("unexpected file field %q (send files as %q)", field, uploadField))
+				return
+			}
 		}
-		if err := r.real.Type(r.duration(payload.TimeoutMs), payload.Selector, payload.Value, payload.Clear); err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
+		// Parts of one field keep the order they were sent in.
+		for _, header := range req.MultipartForm.File[uploadField] {
+			open := func() (io.ReadCloser, error) { return header.Open() }
+			files = append(files, UploadFile{Name: header.Filename, Open: open})
 		}
-		okJSON(w)
-	})
-
-	router.Post("/get-text", func(w http.ResponseWriter, req *http.Request) {
-		var payload textRequest
+	} else {
 		if err := decodeRequest(req, &payload); err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
+			errorJSON(w, uploadErrorStatus(err), err)
 			return
 		}
-		text, err := r.real.GetText(r.duration(payload.TimeoutMs), payload.Selector, payload.Visible)
-		if err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
+		for _, file := range payload.Files {
+			data, err := base64.StdEncoding.DecodeString(file.ContentBase64)
+			if err != nil {
+				errorJSON(w, http.StatusBadRequest, fmt.Errorf("file %q is not valid base64: %w", file.Name, err))
+				return
+			}
+			open := func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(data)), nil }
+			files = append(files, UploadFile{Name: file.Name, Open: open})
 		}
-		respondJSON(w, http.StatusOK, map[string]any{"text": text})
-	})
+	}
+	if payload.Selector == "" {
+		errorJSON(w, http.StatusBadRequest, errors.New("selector required"))
+		return
+	}
+	if len(files) == 0 {
+		errorJSON(w, http.StatusBadRequest, errors.New("at least one file is required"))
+		return
+	}
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	uploaded, err := r.real.Upload(targetID, r.duration(req, payload.TimeoutMs), payload.Selector, files)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, uploadResponse{Status: "ok", Files: uploaded})
+}
 
-	router.Post("/get-html", func(w http.ResponseWriter, req *http.Request) {
-		var payload htmlRequest
-		if err := decodeRequest(req, &payload); err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
-		}
-		html, err := r.real.GetHTML(r.duration(payload.TimeoutMs), payload.Selector)
+// uploadErrorStatus tells an upload over the size limit from a malformed one.
+func uploadErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
+
+func (r *Runtime) handleGetText(w http.ResponseWriter, req *http.Request) {
+	var payload textRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	text, err := r.real.GetText(targetID, r.duration(req, payload.TimeoutMs), payload.Selector, payload.Visible)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, textResponse{Text: text})
+}
+
+func (r *Runtime) handleGetHTML(w http.ResponseWriter, req *http.Request) {
+	var payload htmlRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	html, err := r.real.GetHTML(targetID, r.duration(req, payload.TimeoutMs), payload.Selector)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, htmlResponse{HTML: html})
+}
+
+func (r *Runtime) handleGetAttribute(w http.ResponseWriter, req *http.Request) {
+	var payload attributeRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	value, ok, err := r.real.GetAttribute(targetID, r.duration(req, payload.TimeoutMs), payload.Selector, payload.Name)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, attributeResponse{Value: value, Exists: ok})
+}
+
+func (r *Runtime) handleWaitSelector(w http.ResponseWriter, req *http.Request) {
+	var payload waitSelectorRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	if err := r.real.WaitForSelector(targetID, r.duration(req, payload.TimeoutMs), payload.Selector, payload.Visible); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	okJSON(w)
+}
+
+func (r *Runtime) handleEvaluate(w http.ResponseWriter, req *http.Request) {
+	var payload evaluateRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(payload.Expression) == "" {
+		errorJSON(w, http.StatusBadRequest, errors.New("expression is required"))
+		return
+	}
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	result, err := r.real.Evaluate(targetID, r.duration(req, payload.TimeoutMs), payload.Expression, payload.AwaitPromise)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, evaluateResponse{Result: result})
+}
+
+func (r *Runtime) handleScrape(w http.ResponseWriter, req *http.Request) {
+	var payload scrapeRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(payload.Selector) == "" {
+		errorJSON(w, http.StatusBadRequest, errors.New("selector is required"))
+		return
+	}
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	timeout := r.duration(req, payload.TimeoutMs)
+	if attr := strings.TrimSpace(payload.Attribute); attr != "" {
+		value, exists, err := r.real.GetAttribute(targetID, timeout, payload.Selector, attr)
 		if err != nil {
 			errorJSON(w, http.StatusBadRequest, err)
 			return
 		}
-		respondJSON(w, http.StatusOK, map[string]any{"html": html})
-	})
+		respondJSON(w, http.StatusOK, attributeResponse{Value: value, Exists: exists})
+		return
+	}
+	text, err := r.real.GetText(targetID, timeout, payload.Selector, true)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, attributeResponse{Value: text, Exists: true})
+}
 
-	router.Post("/get-attribute", func(w http.ResponseWriter, req *http.Request) {
-		var payload attributeRequest
-		if err := decodeRequest(req, &payload); err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
+func (r *Runtime) handleFetch(w http.ResponseWriter, req *http.Request) {
+	var payload fetchRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	fetch := FetchRequest{
+		URL:          payload.URL,
+		Method:       payload.Method,
+		Headers:      payload.Headers,
+		Body:         payload.Body,
+		BodyBase64:   payload.BodyBase64,
+		Form:         payload.Form,
+		Multipart:    payload.Multipart,
+		JSON:         payload.JSON,
+		Credentials:  payload.Credentials,
+		MaxBodyBytes: payload.MaxBodyBytes,
+	}
+	if _, err := fetch.spec(); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	response, err := r.real.Fetch(targetID, r.duration(req, payload.TimeoutMs), fetch)
+	if err != nil {
+		errorJSON(w, http.StatusBadGateway, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, response)
+}
+
+func (r *Runtime) handleGraphQL(w http.ResponseWriter, req *http.Request) {
+	var payload graphqlRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	gql := GraphQLRequest{Endpoint: payload.Endpoint, Headers: payload.Headers}
+	if len(payload.Batch) > 0 {
+		if payload.Query != "" || payload.OperationName != "" {
+			errorJSON(w, http.StatusBadRequest, errors.New("set either query or batch, not both"))
 			return
 		}
-		value, ok, err := r.real.GetAttribute(r.duration(payload.TimeoutMs), payload.Selector, payload.Name)
+		gql.Batch = true
+		for _, op := range payload.Batch {
+			gql.Operations = append(gql.Operations, GraphQLOperation{
+				Query:         op.Query,
+				OperationName: op.OperationName,
+				Variables:     op.Variables,
+				Extensions:    op.Extensions,
+			})
+		}
+	} else {
+		gql.Operations = []GraphQLOperation{{
+			Query:         payload.Query,
+			OperationName: payload.OperationName,
+			Variables:     payload.Variables,
+			Extensions:    payload.Extensions,
+		}}
+	}
+	if err := gql.validate(); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	response, err := r.real.GraphQL(targetID, r.duration(req, payload.TimeoutMs), gql)
+	if err != nil {
+		errorJSON(w, http.StatusBadGateway, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, response)
+}
+
+func (r *Runtime) handleProfileAttach(w http.ResponseWriter, req *http.Request) {
+	var payload profileAttachRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var cookies []*network.CookieParam
+	for _, c := range payload.Cookies {
+		cookie, err := convertCookieParam(c)
 		if err != nil {
 			errorJSON(w, http.StatusBadRequest, err)
 			return
 		}
-		respondJSON(w, http.StatusOK, map[string]any{"value": value, "exists": ok})
-	})
+		cookies = append(cookies, cookie)
+	}
 
-	router.Post("/wait-selector", func(w http.ResponseWriter, req *http.Request) {
-		var payload waitSelectorRequest
-		if err := decodeRequest(req, &payload); err != nil {
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+
+	timeout := r.duration(req, payload.Timeout)
+	if len(cookies) > 0 {
+		if err := r.real.SetCookies(targetID, timeout, cookies); err != nil {
 			errorJSON(w, http.StatusBadRequest, err)
 			return
 		}
-		if err := r.real.WaitForSelector(r.duration(payload.TimeoutMs), payload.Selector, payload.Visible); err != nil {
+	}
+
+	if len(payload.Local) > 0 || len(payload.Session) > 0 {
+		storagePayload := StoragePayload{Local: payload.Local, Session: payload.Session}
+		if err := r.real.SetStorage(targetID, timeout, storagePayload); err != nil {
 			errorJSON(w, http.StatusBadRequest, err)
 			return
 		}
-		okJSON(w)
-	})
+	}
+
+	okJSON(w)
 }
 
-func (r *Runtime) mountScriptRoutes(router chi.Router) {
-	router.Post("/evaluate", func(w http.ResponseWriter, req *http.Request) {
-		var payload evaluateRequest
-		if err := decodeRequest(req, &payload); err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
-		}
-		if strings.TrimSpace(payload.Expression) == "" {
-			errorJSON(w, http.StatusBadRequest, errors.New("expression is required"))
-			return
-		}
-		result, err := r.real.Evaluate(r.duration(payload.TimeoutMs), payload.Expression, payload.AwaitPromise)
-		if err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
-		}
-		respondJSON(w, http.StatusOK, map[string]any{"result": result})
+func (r *Runtime) handleProfileExtract(w http.ResponseWriter, req *http.Request) {
+	targetID, ok := r.resolveTarget(w, req, queryTarget(req))
+	if !ok {
+		return
+	}
+	cookies, err := r.real.GetCookies(targetID, r.duration(req, queryTimeout(req)))
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	storage, err := r.real.GetStorage(targetID, r.defaultTimeout)
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, profileResponse{
+		Cookies:        mapCookies(cookies),
+		LocalStorage:   storage.Local,
+		SessionStorage: storage.Session,
 	})
 }
 
-func (r *Runtime) mountActionRoutes(router chi.Router) {
-	router.Post("/navigate", func(w http.ResponseWriter, req *http.Request) {
-		var payload navigateRequest
-		if err := decodeRequest(req, &payload); err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
-		}
-		if strings.TrimSpace(payload.URL) == "" {
-			errorJSON(w, http.StatusBadRequest, errors.New("url is required"))
-			return
-		}
-		if err := r.real.Navigate(r.duration(payload.TimeoutMs), payload.URL); err != nil {
-			errorJSON(w, http.StatusInternalServerError, err)
-			return
-		}
-		okJSON(w)
-	})
+func decodeRequest[T any](r *http.Request, dest *T) error {
+	defer r.Body.Close()
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	return d.Decode(dest)
+}
 
-	router.Post("/screenshot", func(w http.ResponseWriter, req *http.Request) {
-		var payload screenshotRequest
-		_ = decodeRequest(req, &payload)
-		data, err := r.real.Screenshot(r.duration(payload.TimeoutMs), payload.FullPage, payload.Format, payload.Quality)
-		if err != nil {
-			errorJSON(w, http.StatusInternalServerError, err)
-			return
-		}
-		respondJSON(w, http.StatusOK, map[string]any{
-			"data":        base64.StdEncoding.EncodeToString(data),
-			"format":      strings.ToLower(payload.Format),
-			"full_page":   payload.FullPage,
-			"byte_length": len(data),
-			"captured_at": time.Now().UTC().Format(time.RFC3339Nano),
-		})
+func (r *Runtime) handleListRoutes(w http.ResponseWriter, req *http.Request) {
+	targetID, ok := r.resolveTarget(w, req, queryTarget(req))
+	if !ok {
+		return
+	}
+	routes, err := r.real.Routes(targetID)
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, routesResponse{Routes: routes})
+}
+
+func (r *Runtime) handleAddRoute(w http.ResponseWriter, req *http.Request) {
+	var payload routeRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	rule, err := r.real.AddRoute(targetID, r.duration(req, payload.TimeoutMs), RouteRule{
+		URLPattern:   payload.URLPattern,
+		Method:       payload.Method,
+		ResourceType: payload.ResourceType,
+		Action:       payload.Action,
+		Status:       payload.Status,
+		Headers:      payload.Headers,
+		Body:         payload.Body,
+		BodyBase64:   payload.BodyBase64,
+		ErrorReason:  payload.ErrorReason,
+		Times:        payload.Times,
 	})
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, rule)
+}
 
-	router.Post("/scrape", func(w http.ResponseWriter, req *http.Request) {
-		var payload scrapeRequest
-		if err := decodeRequest(req, &payload); err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
-		}
-		if strings.TrimSpace(payload.Selector) == "" {
-			errorJSON(w, http.StatusBadRequest, errors.New("selector is required"))
-			return
-		}
-		timeout := r.duration(payload.TimeoutMs)
-		if attr := strings.TrimSpace(payload.Attribute); attr != "" {
-			value, exists, err := r.real.GetAttribute(timeout, payload.Selector, attr)
-			if err != nil {
-				errorJSON(w, http.StatusBadRequest, err)
-				return
-			}
-			respondJSON(w, http.StatusOK, map[string]any{"value": value, "exists": exists})
-			return
-		}
-		text, err := r.real.GetText(timeout, payload.Selector, true)
-		if err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
-		}
-		respondJSON(w, http.StatusOK, map[string]any{"value": text, "exists": true})
+func (r *Runtime) handleDeleteRoute(w http.ResponseWriter, req *http.Request) {
+	targetID, ok := r.resolveTarget(w, req, queryTarget(req))
+	if !ok {
+		return
+	}
+	err := r.real.DeleteRoute(targetID, r.duration(req, queryTimeout(req)), chi.URLParam(req, "routeID"))
+	if errors.Is(err, ErrUnknownRoute) {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	okJSON(w)
+}
+
+func (r *Runtime) handleClearRoutes(w http.ResponseWriter, req *http.Request) {
+	targetID, ok := r.resolveTarget(w, req, queryTarget(req))
+	if !ok {
+		return
+	}
+	if err := r.real.DeleteRoute(targetID, r.duration(req, queryTimeout(req)), ""); err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	okJSON(w)
+}
+
+func (r *Runtime) handleHARStart(w http.ResponseWriter, req *http.Request) {
+	var payload harStartRequest
+	_ = decodeRequest(req, &payload)
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	status, err := r.real.StartHAR(targetID, r.duration(req, payload.TimeoutMs), HAROptions{
+		IncludeBodies: payload.IncludeBodies,
+		MaxBodyBytes:  payload.MaxBodyBytes,
 	})
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, status)
+}
 
-	router.Post("/evaluate", func(w http.ResponseWriter, req *http.Request) {
-		var payload evaluateRequest
-		if err := decodeRequest(req, &payload); err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
-		}
-		if strings.TrimSpace(payload.Expression) == "" {
-			errorJSON(w, http.StatusBadRequest, errors.New("expression is required"))
-			return
-		}
-		result, err := r.real.Evaluate(r.duration(payload.TimeoutMs), payload.Expression, payload.AwaitPromise)
-		if err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
-		}
-		respondJSON(w, http.StatusOK, map[string]any{"result": result})
+func (r *Runtime) handleHARStop(w http.ResponseWriter, req *http.Request) {
+	var payload targetRequest
+	_ = decodeRequest(req, &payload)
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	status, err := r.real.StopHAR(targetID)
+	if errors.Is(err, ErrHARNotStarted) {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, status)
+}
+
+func (r *Runtime) handleHARExport(w http.ResponseWriter, req *http.Request) {
+	targetID, ok := r.resolveTarget(w, req, queryTarget(req))
+	if !ok {
+		return
+	}
+	har, err := r.real.ExportHAR(targetID)
+	if errors.Is(err, ErrHARNotStarted) {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", targetID+".har"))
+	respondJSON(w, http.StatusOK, har)
+}
+
+func (r *Runtime) handleWebSocketsStart(w http.ResponseWriter, req *http.Request) {
+	var payload webSocketsStartRequest
+	_ = decodeRequest(req, &payload)
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	status, err := r.real.StartWebSockets(targetID, r.duration(req, payload.TimeoutMs), WebSocketOptions{
+		URLPatterns:     payload.URLPatterns,
+		MaxFrames:       payload.MaxFrames,
+		MaxPayloadBytes: payload.MaxPayloadBytes,
 	})
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, status)
+}
 
-	router.Post("/graphql", func(w http.ResponseWriter, req *http.Request) {
-		var payload graphqlRequest
-		if err := decodeRequest(req, &payload); err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
-		}
-		if strings.TrimSpace(payload.Endpoint) == "" {
-			errorJSON(w, http.StatusBadRequest, errors.New("endpoint is required"))
-			return
-		}
-		if strings.TrimSpace(payload.Query) == "" {
-			errorJSON(w, http.StatusBadRequest, errors.New("query is required"))
-			return
-		}
-		if payload.Variables == nil {
-			payload.Variables = map[string]any{}
-		}
-		response, err := r.real.GraphQL(r.duration(payload.TimeoutMs), payload.Endpoint, payload.Query, payload.Variables)
+func (r *Runtime) handleWebSocketsStop(w http.ResponseWriter, req *http.Request) {
+	var payload targetRequest
+	_ = decodeRequest(req, &payload)
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	status, err := r.real.StopWebSockets(targetID)
+	if errors.Is(err, ErrWebSocketsNotStarted) {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, status)
+}
+
+func (r *Runtime) handleWebSocketFrames(w http.ResponseWriter, req *http.Request) {
+	targetID, ok := r.resolveTarget(w, req, queryTarget(req))
+	if !ok {
+		return
+	}
+	var since int64
+	if value := req.URL.Query().Get("since"); value != "" {
+		parsed, err := strconv.ParseInt(value, 10, 64)
 		if err != nil {
-			errorJSON(w, http.StatusBadGateway, err)
+			errorJSON(w, http.StatusBadRequest, fmt.Errorf("invalid since %q", value))
 			return
 		}
-		respondJSON(w, http.StatusOK, response)
-	})
+		since = parsed
+	}
+	status, err := r.real.WebSocketStatus(targetID)
+	if errors.Is(err, ErrWebSocketsNotStarted) {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	frames, err := r.real.WebSocketFrames(targetID, since)
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, webSocketFramesResponse{Capture: status, Frames: frames})
 }
 
-func (r *Runtime) mountProfileRoutes(router chi.Router) {
-	router.Post("/attach", func(w http.ResponseWriter, req *http.Request) {
-		var payload profileAttachRequest
-		if err := decodeRequest(req, &payload); err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
-		}
+func (r *Runtime) handleWebSocketStream(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		errorJSON(w, http.StatusInternalServerError, errors.New("streaming unsupported"))
+		return
+	}
+	targetID, ok := r.resolveTarget(w, req, queryTarget(req))
+	if !ok {
+		return
+	}
+	ch, unsubscribe, err := r.real.SubscribeWebSockets(targetID, 128)
+	if errors.Is(err, ErrWebSocketsNotStarted) {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer unsubscribe()
 
-		var cookies []*network.CookieParam
-		for _, c := range payload.Cookies {
-			cookie, err := convertCookieParam(c)
-			if err != nil {
-				errorJSON(w, http.StatusBadRequest, err)
-				return
-			}
-			cookies = append(cookies, cookie)
-		}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
 
-		timeout := r.duration(payload.Timeout)
-		if len(cookies) > 0 {
-			if err := r.real.SetCookies(timeout, cookies); err != nil {
-				errorJSON(w, http.StatusBadRequest, err)
+	ctx := req.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case frame, ok := <-ch:
+			if !ok {
 				return
 			}
-		}
-
-		if len(payload.Local) > 0 || len(payload.Session) > 0 {
-			storagePayload := StoragePayload{Local: payload.Local, Session: payload.Session}
-			if err := r.real.SetStorage(timeout, storagePayload); err != nil {
-				errorJSON(w, http.StatusBadRequest, err)
-				return
+			data, err := json.Marshal(frame)
+			if err != nil {
+				continue
 			}
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
 		}
+	}
+}
+
+func (r *Runtime) handleGetBlocking(w http.ResponseWriter, req *http.Request) {
+	info, err := r.real.Blocking(sessionID(req))
+	if err != nil {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, info)
+}
 
-		okJSON(w)
+func (r *Runtime) handleSetBlocking(w http.ResponseWriter, req *http.Request) {
+	var payload blockingRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	info, err := r.real.SetBlocking(sessionID(req), r.duration(req, payload.TimeoutMs), BlockPolicy{
+		ResourceTypes: payload.ResourceTypes,
+		URLPatterns:   payload.URLPatterns,
 	})
+	if errors.Is(err, ErrUnknownSession) {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, info)
+}
 
-	router.Get("/extract", func(w http.ResponseWriter, req *http.Request) {
-		cookies, err := r.real.GetCookies(r.duration(queryTimeout(req)))
-		if err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
-		}
-		storage, err := r.real.GetStorage(r.defaultTimeout)
-		if err != nil {
-			errorJSON(w, http.StatusBadRequest, err)
-			return
-		}
+func (r *Runtime) handleGetHeaders(w http.ResponseWriter, req *http.Request) {
+	info, err := r.real.Headers(sessionID(req))
+	if err != nil {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, info)
+}
 
-		respondJSON(w, http.StatusOK, map[string]any{
-			"cookies":         mapCookies(cookies),
-			"local_storage":   storage.Local,
-			"session_storage": storage.Session,
-		})
+func (r *Runtime) handleSetHeaders(w http.ResponseWriter, req *http.Request) {
+	var payload headersRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	info, err := r.real.SetHeaders(sessionID(req), r.duration(req, payload.TimeoutMs), payload.Headers)
+	if errors.Is(err, ErrUnknownSession) {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, info)
+}
+
+func (r *Runtime) handleSetCredentials(w http.ResponseWriter, req *http.Request) {
+	var payload credentialsRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	creds := &Credentials{Username: payload.Username, Password: payload.Password}
+	info, err := r.real.SetCredentials(sessionID(req), r.duration(req, payload.TimeoutMs), creds)
+	if errors.Is(err, ErrUnknownSession) {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, info)
+}
+
+func (r *Runtime) handleClearCredentials(w http.ResponseWriter, req *http.Request) {
+	info, err := r.real.SetCredentials(sessionID(req), r.duration(req, queryTimeout(req)), nil)
+	if errors.Is(err, ErrUnknownSession) {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, info)
+}
+
+func (r *Runtime) handleGetThrottling(w http.ResponseWriter, req *http.Request) {
+	th, err := r.real.Throttling(sessionID(req))
+	if err != nil {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, throttlingResponse{Throttling: th})
+}
+
+func (r *Runtime) handleSetThrottling(w http.ResponseWriter, req *http.Request) {
+	var payload throttlingRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	th, err := r.real.SetThrottling(sessionID(req), r.duration(req, payload.TimeoutMs), &Throttling{
+		Profile:      payload.Profile,
+		Offline:      payload.Offline,
+		LatencyMs:    payload.LatencyMs,
+		DownloadKbps: payload.DownloadKbps,
+		UploadKbps:   payload.UploadKbps,
+		CPURate:      payload.CPURate,
 	})
+	if errors.Is(err, ErrUnknownSession) {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, throttlingResponse{Throttling: th})
 }
 
-func decodeRequest[T any](r *http.Request, dest *T) error {
-	defer r.Body.Close()
-	d := json.NewDecoder(r.Body)
-	d.DisallowUnknownFields()
-	return d.Decode(dest)
+func (r *Runtime) handleClearThrottling(w http.ResponseWriter, req *http.Request) {
+	_, err := r.real.SetThrottling(sessionID(req), r.duration(req, queryTimeout(req)), nil)
+	if errors.Is(err, ErrUnknownSession) {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, throttlingResponse{})
+}
+
+func (r *Runtime) handleGetCertificates(w http.ResponseWriter, req *http.Request) {
+	info, err := r.real.Certificates(sessionID(req))
+	if err != nil {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, info)
+}
+
+func (r *Runtime) handleSetCertificates(w http.ResponseWriter, req *http.Request) {
+	var payload certificatesRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	info, err := r.real.SetIgnoreCertificateErrors(sessionID(req), r.duration(req, payload.TimeoutMs), payload.IgnoreErrors)
+	if errors.Is(err, ErrUnknownSession) {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, info)
+}
+
+func (r *Runtime) handleListDownloads(w http.ResponseWriter, req *http.Request) {
+	downloads, err := r.real.Downloads(sessionID(req))
+	if err != nil {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, downloadsResponse{Downloads: downloads})
+}
+
+func (r *Runtime) handleGetDownload(w http.ResponseWriter, req *http.Request) {
+	download, file, err := r.real.OpenDownload(sessionID(req), chi.URLParam(req, "downloadID"))
+	switch {
+	case errors.Is(err, ErrUnknownSession), errors.Is(err, ErrUnknownDownload):
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	case errors.Is(err, ErrDownloadInProgress):
+		errorJSON(w, http.StatusConflict, err)
+		return
+	case err != nil:
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	defer file.Close()
+	contentType := mime.TypeByExtension(filepath.Ext(download.Filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", download.Filename))
+	modified := download.StartedAt
+	if download.FinishedAt != nil {
+		modified = *download.FinishedAt
+	}
+	http.ServeContent(w, req, download.Filename, modified, file)
+}
+
+func (r *Runtime) handleDeleteDownload(w http.ResponseWriter, req *http.Request) {
+	err := r.real.DeleteDownload(sessionID(req), r.duration(req, queryTimeout(req)), chi.URLParam(req, "downloadID"))
+	if errors.Is(err, ErrUnknownSession) || errors.Is(err, ErrUnknownDownload) {
+		errorJSON(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	okJSON(w)
+}
+
+func (r *Runtime) handleWaitResponse(w http.ResponseWriter, req *http.Request) {
+	var payload waitResponseRequest
+	if err := decodeRequest(req, &payload); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	wait := ResponseWait{
+		URLPattern:   payload.URLPattern,
+		Method:       payload.Method,
+		ResourceType: payload.ResourceType,
+		Trigger:      payload.Trigger,
+		MaxBodyBytes: payload.MaxBodyBytes,
+	}
+	if err := wait.validate(); err != nil {
+		errorJSON(w, http.StatusBadRequest, err)
+		return
+	}
+	targetID, ok := r.resolveTarget(w, req, payload.TargetID)
+	if !ok {
+		return
+	}
+	response, err := r.real.WaitForResponse(targetID, r.duration(req, payload.TimeoutMs), wait)
+	if errors.Is(err, context.DeadlineExceeded) {
+		errorJSON(w, http.StatusGatewayTimeout, err)
+		return
+	}
+	if err != nil {
+		errorJSON(w, http.StatusInternalServerError, err)
+		return
+	}
+	respondJSON(w, http.StatusOK, response)
 }
 
 func respondJSON(w http.ResponseWriter, status int, payload any) {
@@ -393,11 +1160,14 @@ func respondJSON(w http.ResponseWriter, status int, payload any) {
 }
 
 func errorJSON(w http.ResponseWriter, status int, err error) {
-	respondJSON(w, status, map[string]any{"error": err.Error()})
+	if errors.Is(err, ErrQueueFull) {
+		status = http.StatusTooManyRequests
+	}
+	respondJSON(w, status, errorResponse{Error: err.Error()})
 }
 
 func okJSON(w http.ResponseWriter) {
-	respondJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+	respondJSON(w, http.StatusOK, statusResponse{Status: "ok"})
 }
 
 func queryTimeout(r *http.Request) int64 {
@@ -409,19 +1179,49 @@ func queryTimeout(r *http.Request) int64 {
 	return 0
 }
 
+func queryTarget(r *http.Request) string {
+	return strings.TrimSpace(r.URL.Query().Get("target_id"))
+}
+
 // Request payloads ----------------------------------------------------------
 
 type navigateRequest struct {
+	TargetID     string            `json:"target_id"`
+	URL          string            `json:"url"`
+	Headers      map[string]string `json:"headers"`
+	Credentials  *Credentials      `json:"credentials"`
+	Block        *BlockPolicy      `json:"block"`
+	WaitUntil    string            `json:"wait_until"`
+	FailOnStatus bool              `json:"fail_on_status"`
+	TimeoutMs    int64             `json:"timeout_ms"`
+}
+
+type newTabRequest struct {
 	URL       string `json:"url"`
+	Activate  bool   `json:"activate"`
 	TimeoutMs int64  `json:"timeout_ms"`
 }
 
+type createSessionRequest struct {
+	IdleTimeoutMs    int64             `json:"idle_timeout_ms"`
+	Block            *BlockPolicy      `json:"block"`
+	Headers          map[string]string `json:"headers"`
+	Credentials      *Credentials      `json:"credentials"`
+	Throttling       *Throttling       `json:"throttling"`
+	Proxy            *ProxyConfig      `json:"proxy"`
+	IgnoreCertErrors *bool             `json:"ignore_cert_errors"`
+	TimeoutMs        int64             `json:"timeout_ms"`
+}
+
 type reloadRequest struct {
-	IgnoreCache bool  `json:"ignore_cache"`
-	TimeoutMs   int64 `json:"timeout_ms"`
+	TargetID    string `json:"target_id"`
+	IgnoreCache bool   `json:"ignore_cache"`
+	WaitUntil   string `json:"wait_until"`
+	TimeoutMs   int64  `json:"timeout_ms"`
 }
 
 type viewportRequest struct {
+	TargetID  string  `json:"target_id"`
 	Width     int     `json:"width"`
 	Height    int     `json:"height"`
 	Scale     float64 `json:"scale"`
@@ -430,6 +1230,7 @@ type viewportRequest struct {
 }
 
 type userAgentRequest struct {
+	TargetID       string `json:"target_id"`
 	UserAgent      string `json:"user_agent"`
 	AcceptLanguage string `json:"accept_language"`
 	Platform       string `json:"platform"`
@@ -437,10 +1238,13 @@ type userAgentRequest struct {
 }
 
 type waitNavigationRequest struct {
-	TimeoutMs int64 `json:"timeout_ms"`
+	TargetID  string `json:"target_id"`
+	WaitUntil string `json:"wait_until"`
+	TimeoutMs int64  `json:"timeout_ms"`
 }
 
 type screenshotRequest struct {
+	TargetID  string `json:"target_id"`
 	FullPage  bool   `json:"full_page"`
 	Format    string `json:"format"`
 	Quality   int    `json:"quality"`
@@ -448,58 +1252,184 @@ type screenshotRequest struct {
 }
 
 type clickRequest struct {
+	TargetID  string `json:"target_id"`
 	Selector  string `json:"selector"`
 	Button    string `json:"button"`
 	TimeoutMs int64  `json:"timeout_ms"`
 }
 
 type typeRequest struct {
+	TargetID  string `json:"target_id"`
 	Selector  string `json:"selector"`
 	Value     string `json:"value"`
 	Clear     bool   `json:"clear"`
 	TimeoutMs int64  `json:"timeout_ms"`
 }
 
+// uploadRequest is the JSON form of an upload; multipart requests carry the
+// same fields as form values and the files as file parts.
+type uploadRequest struct {
+	TargetID  string       `json:"target_id"`
+	Selector  string       `json:"selector"`
+	Files     []uploadFile `json:"files"`
+	TimeoutMs int64        `json:"timeout_ms"`
+}
+
+type uploadFile struct {
+	Name          string `json:"name"`
+	ContentBase64 string `json:"content_base64"`
+}
+
 type textRequest struct {
+	TargetID  string `json:"target_id"`
 	Selector  string `json:"selector"`
 	Visible   bool   `json:"visible"`
 	TimeoutMs int64  `json:"timeout_ms"`
 }
 
 type htmlRequest struct {
+	TargetID  string `json:"target_id"`
 	Selector  string `json:"selector"`
 	TimeoutMs int64  `json:"timeout_ms"`
 }
 
 type attributeRequest struct {
+	TargetID  string `json:"target_id"`
 	Selector  string `json:"selector"`
 	Name      string `json:"name"`
 	TimeoutMs int64  `json:"timeout_ms"`
 }
 
 type waitSelectorRequest struct {
+	TargetID  string `json:"target_id"`
 	Selector  string `json:"selector"`
 	Visible   bool   `json:"visible"`
 	TimeoutMs int64  `json:"timeout_ms"`
 }
 
 type evaluateRequest struct {
+	TargetID     string `json:"target_id"`
 	Expression   string `json:"expression"`
 	AwaitPromise bool   `json:"await_promise"`
 	TimeoutMs    int64  `json:"timeout_ms"`
 }
 
 type scrapeRequest struct {
+	TargetID  string `json:"target_id"`
 	Selector  string `json:"selector"`
 	Attribute string `json:"attribute"`
 	TimeoutMs int64  `json:"timeout_ms"`
 }
 
+type fetchRequest struct {
+	TargetID     string            `json:"target_id"`
+	URL          string            `json:"url"`
+	Method       string            `json:"method"`
+	Headers      map[string]string `json:"headers"`
+	Body         string            `json:"body"`
+	BodyBase64   bool              `json:"body_base64"`
+	Form         map[string]string `json:"form"`
+	Multipart    bool              `json:"multipart"`
+	JSON         any               `json:"json"`
+	Credentials  string            `json:"credentials"`
+	MaxBodyBytes int64             `json:"max_body_bytes"`
+	TimeoutMs    int64             `json:"timeout_ms"`
+}
+
 type graphqlRequest struct {
-	Endpoint  string         `json:"endpoint"`
-	Query     string         `json:"query"`
-	Variables map[string]any `json:"variables"`
-	TimeoutMs int64          `json:"timeout_ms"`
+	TargetID      string             `json:"target_id"`
+	Endpoint      string             `json:"endpoint"`
+	Query         string             `json:"query"`
+	OperationName string             `json:"operation_name"`
+	Variables     map[string]any     `json:"variables"`
+	Extensions    map[string]any     `json:"extensions"`
+	Headers       map[string]string  `json:"headers"`
+	Batch         []graphqlOperation `json:"batch"`
+	TimeoutMs     int64              `json:"timeout_ms"`
+}
+
+type graphqlOperation struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operation_name"`
+	Variables     map[string]any `json:"variables"`
+	Extensions    map[string]any `json:"extensions"`
+}
+
+type routeRequest struct {
+	TargetID     string            `json:"target_id"`
+	URLPattern   string            `json:"url_pattern"`
+	Method       string            `json:"method"`
+	ResourceType string            `json:"resource_type"`
+	Action       string            `json:"action"`
+	Status       int               `json:"status"`
+	Headers      map[string]string `json:"headers"`
+	Body         string            `json:"body"`
+	BodyBase64   bool              `json:"body_base64"`
+	ErrorReason  string            `json:"error_reason"`
+	Times        int               `json:"times"`
+	TimeoutMs    int64             `json:"timeout_ms"`
+}
+
+type targetRequest struct {
+	TargetID  string `json:"target_id"`
+	TimeoutMs int64  `json:"timeout_ms"`
+}
+
+type blockingRequest struct {
+	ResourceTypes []string `json:"resource_types"`
+	URLPatterns   []string `json:"url_patterns"`
+	TimeoutMs     int64    `json:"timeout_ms"`
+}
+
+type headersRequest struct {
+	Headers   map[string]string `json:"headers"`
+	TimeoutMs int64             `json:"timeout_ms"`
+}
+
+type credentialsRequest struct {
+	Username  string `json:"username"`
+	Password  string `json:"password"`
+	TimeoutMs int64  `json:"timeout_ms"`
+}
+
+type throttlingRequest struct {
+	Profile      string  `json:"profile"`
+	Offline      bool    `json:"offline"`
+	LatencyMs    float64 `json:"latency_ms"`
+	DownloadKbps float64 `json:"download_kbps"`
+	UploadKbps   float64 `json:"upload_kbps"`
+	CPURate      float64 `json:"cpu_rate"`
+	TimeoutMs    int64   `json:"timeout_ms"`
+}
+
+type certificatesRequest struct {
+	IgnoreErrors bool  `json:"ignore_errors"`
+	TimeoutMs    int64 `json:"timeout_ms"`
+}
+
+type waitResponseRequest struct {
+	TargetID     string           `json:"target_id"`
+	URLPattern   string           `json:"url_pattern"`
+	Method       string           `json:"method"`
+	ResourceType string           `json:"resource_type"`
+	Trigger      *ResponseTrigger `json:"trigger"`
+	MaxBodyBytes int64            `json:"max_body_bytes"`
+	TimeoutMs    int64            `json:"timeout_ms"`
+}
+
+type harStartRequest struct {
+	TargetID      string `json:"target_id"`
+	IncludeBodies bool   `json:"include_bodies"`
+	MaxBodyBytes  int64  `json:"max_body_bytes"`
+	TimeoutMs     int64  `json:"timeout_ms"`
+}
+
+type webSocketsStartRequest struct {
+	TargetID        string   `json:"target_id"`
+	URLPatterns     []string `json:"url_patterns"`
+	MaxFrames       int      `json:"max_frames"`
+	MaxPayloadBytes int      `json:"max_payload_bytes"`
+	TimeoutMs       int64    `json:"timeout_ms"`
 }
 
 type cookieParamRequest struct {
@@ -514,10 +1444,101 @@ type cookieParamRequest struct {
 }
 
 type profileAttachRequest struct {
-	Cookies []cookieParamRequest `json:"cookies"`
-	Local   map[string]string    `json:"local_storage"`
-	Session map[string]string    `json:"session_storage"`
-	Timeout int64                `json:"timeout_ms"`
+	TargetID string               `json:"target_id"`
+	Cookies  []cookieParamRequest `json:"cookies"`
+	Local    map[string]string    `json:"local_storage"`
+	Session  map[string]string    `json:"session_storage"`
+	Timeout  int64                `json:"timeout_ms"`
+}
+
+// Response shapes -----------------------------------------------------------
+//
+// These document the bodies the handlers above write; they feed the OpenAPI
+// document served at /v1/openapi.json.
+
+type statusResponse struct {
+	Status string `json:"status"`
+}
+
+type navigateResponse struct {
+	Status     string           `json:"status"`
+	Navigation NavigationResult `json:"navigation"`
+}
+
+type throttlingResponse struct {
+	Throttling *Throttling `json:"throttling"`
+}
+
+type webSocketFramesResponse struct {
+	Capture WebSocketStatus  `json:"capture"`
+	Frames  []WebSocketFrame `json:"frames"`
+}
+
+type downloadsResponse struct {
+	Downloads []Download `json:"downloads"`
+}
+
+type uploadResponse struct {
+	Status string         `json:"status"`
+	Files  []UploadedFile `json:"files"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type screenshotResponse struct {
+	Data       string `json:"data"`
+	Format     string `json:"format"`
+	FullPage   bool   `json:"full_page"`
+	ByteLength int    `json:"byte_length"`
+	CapturedAt string `json:"captured_at"`
+}
+
+type tabsResponse struct {
+	Tabs []TargetInfo `json:"tabs"`
+}
+
+type sessionsResponse struct {
+	Sessions []SessionInfo `json:"sessions"`
+}
+
+type textResponse struct {
+	Text string `json:"text"`
+}
+
+type htmlResponse struct {
+	HTML string `json:"html"`
+}
+
+type attributeResponse struct {
+	Value  string `json:"value"`
+	Exists bool   `json:"exists"`
+}
+
+type evaluateResponse struct {
+	Result any `json:"result"`
+}
+
+type routesResponse struct {
+	Routes []RouteRule `json:"routes"`
+}
+
+type cookieResponse struct {
+	Name     string  `json:"name"`
+	Value    string  `json:"value"`
+	Domain   string  `json:"domain"`
+	Path     string  `json:"path"`
+	HTTPOnly bool    `json:"http_only"`
+	Secure   bool    `json:"secure"`
+	SameSite string  `json:"same_site"`
+	Expires  float64 `json:"expires"`
+}
+
+type profileResponse struct {
+	Cookies        []cookieResponse  `json:"cookies"`
+	LocalStorage   map[string]string `json:"local_storage"`
+	SessionStorage map[string]string `json:"session_storage"`
 }
 
 func convertCookieParam(req cookieParamRequest) (*network.CookieParam, error) {
@@ -551,20 +1572,19 @@ func convertCookieParam(req cookieParamRequest) (*network.CookieParam, error) {
 	return cookie, nil
 }
 
-func mapCookies(cookies []*network.Cookie) []map[string]any {
-	result := make([]map[string]any, 0, len(cookies))
+func mapCookies(cookies []*network.Cookie) []cookieResponse {
+	result := make([]cookieResponse, 0, len(cookies))
 	for _, c := range cookies {
-		item := map[string]any{
-			"name":      c.Name,
-			"value":     c.Value,
-			"domain":    c.Domain,
-			"path":      c.Path,
-			"http_only": c.HTTPOnly,
-			"secure":    c.Secure,
-			"same_site": strings.ToLower(string(c.SameSite)),
-		}
-		item["expires"] = c.Expires
-		result = append(result, item)
+		result = append(result, cookieResponse{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   c.Domain,
+			Path:     c.Path,
+			HTTPOnly: c.HTTPOnly,
+			Secure:   c.Secure,
+			SameSite: strings.ToLower(string(c.SameSite)),
+			Expires:  c.Expires,
+		})
 	}
 	return result
 }
@@ -0,0 +1,276 @@
+package browser
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ccheshirecat/volant/pkg/pluginspec"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/target"
+	"github.com/go-chi/chi/v5"
+)
+
+// offlineRuntime mounts the runtime over a browser that never launched
+// Chrome. The default context has one tab with HAR and WebSocket recordings
+// and a finished download; session "s1" has no tabs, so its settings change
+// without a CDP round trip.
+func offlineRuntime(t *testing.T, manifest *pluginspec.Manifest) (*Runtime, http.Handler) {
+	t.Helper()
+	dir := t.TempDir()
+	workers := make(chan struct{}, 1)
+	b := &Browser{
+		cfg:      BrowserConfig{UserDataDir: dir, QueueSize: 4},
+		log:      newLogEmitter(),
+		ctx:      context.Background(),
+		workers:  workers,
+		tabs:     map[target.ID]*tab{},
+		active:   map[string]target.ID{},
+		sessions: map[string]*session{"s1": {id: "s1", contextID: "ctx-1", created: time.Now().UTC(), lastUsed: time.Now().UTC()}},
+		policies: map[string]*sessionPolicy{},
+	}
+	queue := newActionQueue(4, workers)
+	t.Cleanup(queue.close)
+	b.tabs["tab1"] = &tab{
+		id:        "tab1",
+		ctx:       context.Background(),
+		created:   time.Now().UTC(),
+		queue:     queue,
+		intercept: newInterceptor(),
+		har: &harRecorder{
+			cancel:    func() {},
+			started:   time.Now().UTC(),
+			recording: true,
+			pending:   map[network.RequestID]*harPending{},
+		},
+		ws: &wsRecorder{
+			cancel:    func() {},
+			started:   time.Now().UTC(),
+			recording: true,
+			urls:      map[network.RequestID]string{},
+			frames:    []WebSocketFrame{{Seq: 1, URL: "wss://example.com/socket", Direction: "received", Opcode: 1, Data: "hello"}},
+			seq:       1,
+			subs:      map[int64]*wsSubscriber{},
+		},
+	}
+	b.active[""] = "tab1"
+
+	file := filepath.Join(dir, "report.pdf")
+	if err := os.WriteFile(file, []byte("%PDF"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	finished := time.Now().UTC()
+	b.policy("").beginDownload(&Download{ID: "d1", TargetID: "tab1", Filename: "report.pdf", State: DownloadCompleted, FinishedAt: &finished, path: file})
+
+	r := &Runtime{real: b, defaultTimeout: time.Second, manifest: manifest}
+	router := chi.NewRouter()
+	if err := r.MountRoutes(router); err != nil {
+		t.Fatalf("MountRoutes: %v", err)
+	}
+	return r, router
+}
+
+// chromeActions cannot answer 200 without a CDP round trip.
+var chromeActions = map[string]bool{
+	"navigate": true, "reload": true, "back": true, "forward": true, "viewport": true,
+	"user_agent": true, "wait_navigation": true, "screenshot": true, "scrape": true,
+	"fetch": true, "graphql": true, "tabs_list": true, "tab_open": true,
+	"tab_activate": true, "tab_close": true, "route_add": true, "route_delete": true,
+	"routes_clear": true, "wait_response": true, "har_start": true,
+	"websockets_start": true, "click": true, "type": true, "upload": true,
+	"get_text": true, "get_html": true, "get_attribute": true, "wait_selector": true,
+	"evaluate": true, "profile_attach": true, "profile_extract": true,
+	"session_create": true, "session_delete": true,
+}
+
+func TestHandlersEncodeDeclaredResponse(t *testing.T) {
+	r, router := offlineRuntime(t, nil)
+	tests := []struct {
+		action  string
+		session string
+		query   string
+		body    string
+	}{
+		{action: "openapi"},
+		{action: "queue_stats"},
+		{action: "sessions_list"},
+		{action: "routes_list"},
+		{action: "blocking_get"},
+		{action: "blocking_set", session: "s1", body: `{"resource_types":["image"],"url_patterns":["*.png"]}`},
+		{action: "headers_get"},
+		{action: "headers_set", session: "s1", body: `{"headers":{"X-Test":"1"}}`},
+		{action: "credentials_set", session: "s1", body: `{"username":"alice","password":"s3cret"}`},
+		{action: "credentials_clear", session: "s1"},
+		{action: "throttling_get"},
+		{action: "throttling_set", session: "s1", body: `{"profile":"slow-3g"}`},
+		{action: "throttling_clear", session: "s1"},
+		{action: "certificates_get"},
+		{action: "certificates_set", session: "s1", body: `{"ignore_errors":true}`},
+		{action: "websocket_frames", query: "since=0"},
+		{action: "websockets_stop", body: `{}`},
+		{action: "har_export"},
+		{action: "har_stop", body: `{}`},
+		{action: "downloads_list"},
+		{action: "download_delete"},
+	}
+
+	actions := make(map[string]Action)
+	for _, action := range r.mounted {
+		actions[action.Name] = action
+	}
+	covered := make(map[string]bool)
+	for _, tt := range tests {
+		covered[tt.action] = true
+		action := actions[tt.action]
+		path := strings.NewReplacer("{downloadID}", "d1").Replace(action.Path)
+		if tt.query != "" {
+			path += "?" + tt.query
+		}
+		req := httptest.NewRequest(action.Method, path, strings.NewReader(tt.body))
+		if tt.session != "" {
+			req.Header.Set(SessionHeader, tt.session)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s %s %s = %d %s", tt.action, action.Method, path, rec.Code, rec.Body)
+			continue
+		}
+
+		// Decoding fails on any key the declared type does not have.
+		decoded := reflect.New(reflect.TypeOf(action.Response))
+		dec := json.NewDecoder(rec.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(decoded.Interface()); err != nil {
+			t.Errorf("%s: body does not decode into %T: %v", tt.action, action.Response, err)
+		}
+	}
+
+	for name, action := range actions {
+		if action.Response == nil || covered[name] || chromeActions[name] {
+			continue
+		}
+		t.Errorf("action %s declares %T but has no handler test", name, action.Response)
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	manifest := &pluginspec.Manifest{Version: "1.2.3", Actions: map[string]pluginspec.Action{
+		"navigate":      {Path: "/v1/browser/actions/navigate", TimeoutMs: 1234},
+		"tab_close":     {},
+		"openapi":       {},
+		"sessions_list": {},
+		"lookup":        {},
+	}}
+	r := &Runtime{manifest: manifest}
+	r.RegisterAction(Action{
+		Name: "lookup", Description: "Look up a record", Method: http.MethodGet, Path: "/v1/records/{id:[0-9]+}",
+		Handler: func(http.ResponseWriter, *http.Request) {}, Response: struct {
+			Note *string `json:"note"`
+		}{},
+	})
+	router := chi.NewRouter()
+	if err := r.MountRoutes(router); err != nil {
+		t.Fatalf("MountRoutes: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /v1/openapi.json = %d %s", rec.Code, rec.Body)
+	}
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Version string `json:"version"`
+		} `json:"info"`
+		Paths map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			Summary     string `json:"summary"`
+			TimeoutMs   int    `json:"x-timeout-ms"`
+			Parameters  []struct {
+				Name     string `json:"name"`
+				In       string `json:"in"`
+				Required bool   `json:"required"`
+			} `json:"parameters"`
+			RequestBody map[string]any            `json:"requestBody"`
+			Responses   map[string]map[string]any `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if doc.OpenAPI != openAPIVersion || doc.Info.Version != "1.2.3" {
+		t.Fatalf("openapi = %q, version = %q", doc.OpenAPI, doc.Info.Version)
+	}
+	if len(doc.Paths) != len(manifest.Actions) {
+		t.Fatalf("got %d paths, want one per declared action: %v", len(doc.Paths), doc.Paths)
+	}
+
+	navigate := doc.Paths["/v1/browser/actions/navigate"]["post"]
+	if navigate.OperationID != "navigate" || navigate.TimeoutMs != 1234 || navigate.RequestBody == nil {
+		t.Fatalf("navigate operation = %+v, want it at the declared path and timeout", navigate)
+	}
+	params := func(op string, path, method string) map[string]string {
+		found := make(map[string]string)
+		for _, p := range doc.Paths[path][method].Parameters {
+			found[p.Name] = p.In
+			if p.In == "path" && !p.Required {
+				t.Errorf("%s: path parameter %s is optional", op, p.Name)
+			}
+		}
+		return found
+	}
+	if got := params("tab_close", "/v1/browser/tabs/{targetID}", "delete"); got["targetID"] != "path" || got["timeout_ms"] != "query" || got[SessionHeader] != "header" {
+		t.Fatalf("tab_close parameters = %v", got)
+	}
+	// Regexp constraints are dropped and extra actions take no session.
+	if got := params("lookup", "/v1/records/{id}", "get"); len(got) != 1 || got["id"] != "path" {
+		t.Fatalf("lookup parameters = %v", got)
+	}
+
+	sessions := doc.Components.Schemas["SessionsResponse"]["properties"].(map[string]any)
+	if _, ok := sessions["sessions"]; !ok || len(sessions) != 1 {
+		t.Fatalf("SessionsResponse properties = %v", sessions)
+	}
+	lookup := doc.Paths["/v1/records/{id}"]["get"].Responses["200"]
+	schema := lookup["content"].(map[string]any)["application/json"].(map[string]any)["schema"].(map[string]any)
+	note := schema["properties"].(map[string]any)["note"].(map[string]any)
+	if note["type"] != "string" || note["nullable"] != true {
+		t.Fatalf("lookup note schema = %v", note)
+	}
+
+	// Every reference resolves to a component.
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok {
+				name := strings.TrimPrefix(ref, "#/components/schemas/")
+				if _, ok := doc.Components.Schemas[name]; !ok {
+					t.Errorf("dangling reference %s", ref)
+				}
+			}
+			for _, child := range v {
+				walk(child)
+			}
+		case []any:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	var raw any
+	_ = json.Unmarshal(rec.Body.Bytes(), &raw)
+	walk(raw)
+}
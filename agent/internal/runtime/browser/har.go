@@ -0,0 +1,521 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	harVersion             = "1.2"
+	harCreator             = "volant-browser"
+	defaultHARMaxBodyBytes = 1 << 20
+	maxHAREntries          = 10000
+	harBodyWait            = 5 * time.Second
+)
+
+// ErrHARNotStarted is returned when exporting a target that has never been
+// recorded.
+var ErrHARNotStarted = errors.New("browser: har recording not started")
+
+// HAROptions configure a HAR recording.
+type HAROptions struct {
+	IncludeBodies bool
+	MaxBodyBytes  int64
+}
+
+// HARStatus describes a target's HAR recording.
+type HARStatus struct {
+	TargetID      string     `json:"target_id"`
+	Recording     bool       `json:"recording"`
+	Entries       int        `json:"entries"`
+	Dropped       int        `json:"dropped"`
+	IncludeBodies bool       `json:"include_bodies"`
+	StartedAt     time.Time  `json:"started_at"`
+	StoppedAt     *time.Time `json:"stopped_at,omitempty"`
+}
+
+// HAR is an HTTP Archive 1.2 document.
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the root of a HAR document.
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Pages   []any      `json:"pages"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator names the application that produced the archive.
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is a single request/response pair.
+type HAREntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+	ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+	Connection      string      `json:"connection,omitempty"`
+	Comment         string      `json:"comment,omitempty"`
+}
+
+// HARRequest is the request half of an entry.
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARResponse is the response half of an entry.
+type HARResponse struct {
+	Status      int64          `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+// HARNameValue is a header, cookie or query parameter.
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData is a request body.
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARContent is a response body.
+type HARContent struct {
+	Size     int64  `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// HARTimings breaks an entry's time into phases, in milliseconds. Phases that
+// do not apply are -1.
+type HARTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+	SSL     float64 `json:"ssl"`
+}
+
+// harRecorder collects Network domain events for one tab.
+type harRecorder struct {
+	mu        sync.Mutex
+	cancel    context.CancelFunc
+	opts      HAROptions
+	started   time.Time
+	stopped   time.Time
+	recording bool
+	entries   []*harPending
+	pending   map[network.RequestID]*harPending
+	dropped   int
+	bodies    sync.WaitGroup
+}
+
+type harPending struct {
+	entry  HAREntry
+	start  time.Time
+	timing *network.ResourceTiming
+}
+
+// StartHAR begins recording the target's traffic, discarding any previous
+// recording.
+func (b *Browser) StartHAR(targetID string, timeout time.Duration, opts HAROptions) (HARStatus, error) {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = defaultHARMaxBodyBytes
+	}
+	t, err := b.tab(targetID)
+	if err != nil {
+		return HARStatus{}, err
+	}
+	err = b.run(targetID, timeout, "har_start", fmt.Sprintf("Recording HAR (bodies=%t)", opts.IncludeBodies), func(ctx context.Context) (string, error) {
+		lctx, cancel := context.WithCancel(t.ctx)
+		rec := &harRecorder{
+			cancel:    cancel,
+			opts:      opts,
+			started:   time.Now().UTC(),
+			recording: true,
+			pending:   make(map[network.RequestID]*harPending),
+		}
+		b.tabsMu.Lock()
+		previous := t.har
+		t.har = rec
+		b.tabsMu.Unlock()
+		if previous != nil {
+			previous.stop()
+		}
+
+		chromedp.ListenTarget(lctx, func(ev any) { b.recordHAR(t, rec, ev) })
+		return "", nil
+	})
+	if err != nil {
+		return HARStatus{}, err
+	}
+	return b.HARStatus(targetID)
+}
+
+// StopHAR stops recording; the captured entries stay available for export
+// until the next StartHAR.
+func (b *Browser) StopHAR(targetID string) (HARStatus, error) {
+	rec, t, err := b.harRecorder(targetID)
+	if err != nil {
+		return HARStatus{}, err
+	}
+	rec.stop()
+	b.publish("agent", fmt.Sprintf("HAR recording stopped on %s", t.id))
+	return rec.status(string(t.id)), nil
+}
+
+// HARStatus reports the state of the target's recording.
+func (b *Browser) HARStatus(targetID string) (HARStatus, error) {
+	rec, t, err := b.harRecorder(targetID)
+	if err != nil {
+		return HARStatus{}, err
+	}
+	return rec.status(string(t.id)), nil
+}
+
+// ExportHAR returns the target's recording as a HAR document. Requests still
+// in flight are included with the data gathered so far.
+func (b *Browser) ExportHAR(targetID string) (HAR, error) {
+	rec, _, err := b.harRecorder(targetID)
+	if err != nil {
+		return HAR{}, err
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	entries := make([]HAREntry, 0, len(rec.entries))
+	for _, p := range rec.entries {
+		entries = append(entries, p.entry)
+	}
+	return HAR{Log: HARLog{
+		Version: harVersion,
+		Creator: HARCreator{Name: harCreator, Version: harVersion},
+		Pages:   []any{},
+		Entries: entries,
+	}}, nil
+}
+
+func (b *Browser) harRecorder(targetID string) (*harRecorder, *tab, error) {
+	t, err := b.tab(targetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	b.tabsMu.RLock()
+	rec := t.har
+	b.tabsMu.RUnlock()
+	if rec == nil {
+		return nil, nil, ErrHARNotStarted
+	}
+	return rec, t, nil
+}
+
+func (rec *harRecorder) stop() {
+	rec.mu.Lock()
+	if !rec.recording {
+		rec.mu.Unlock()
+		return
+	}
+	rec.recording = false
+	rec.stopped = time.Now().UTC()
+	rec.cancel()
+	rec.mu.Unlock()
+
+	// Give in-flight body fetches a chance to land before the export.
+	done := make(chan struct{})
+	go func() {
+		rec.bodies.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(harBodyWait):
+	}
+}
+
+func (rec *harRecorder) status(targetID string) HARStatus {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	status := HARStatus{
+		TargetID:      targetID,
+		Recording:     rec.recording,
+		Entries:       len(rec.entries),
+		Dropped:       rec.dropped,
+		IncludeBodies: rec.opts.IncludeBodies,
+		StartedAt:     rec.started,
+	}
+	if !rec.stopped.IsZero() {
+		stopped := rec.stopped
+		status.StoppedAt = &stopped
+	}
+	return status
+}
+
+func (b *Browser) recordHAR(t *tab, rec *harRecorder, ev any) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if !rec.recording {
+		return
+	}
+
+	switch e := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		// A redirect reuses the request ID: close out the previous hop with
+		// the redirect response before starting the next one.
+		if prev, ok := rec.pending[e.RequestID]; ok && e.RedirectResponse != nil {
+			prev.applyResponse(e.RedirectResponse)
+			prev.entry.Response.RedirectURL = e.Request.URL
+			prev.finish(monotonic(e.Timestamp))
+			delete(rec.pending, e.RequestID)
+		}
+		if len(rec.entries) >= maxHAREntries {
+			rec.dropped++
+			return
+		}
+		p := &harPending{start: monotonic(e.Timestamp)}
+		p.entry = HAREntry{
+			StartedDateTime: wallTime(e).Format(time.RFC3339Nano),
+			Request:         harRequest(e.Request),
+			Response: HARResponse{
+				Cookies: []HARNameValue{},
+				Headers: []HARNameValue{},
+			},
+			Timings: HARTimings{Blocked: -1, DNS: -1, Connect: -1, Send: 0, Wait: 0, Receive: 0, SSL: -1},
+		}
+		rec.entries = append(rec.entries, p)
+		rec.pending[e.RequestID] = p
+
+	case *network.EventResponseReceived:
+		if p, ok := rec.pending[e.RequestID]; ok {
+			p.applyResponse(e.Response)
+		}
+
+	case *network.EventLoadingFinished:
+		p, ok := rec.pending[e.RequestID]
+		if !ok {
+			return
+		}
+		delete(rec.pending, e.RequestID)
+		p.entry.Response.BodySize = int64(e.EncodedDataLength)
+		p.finish(monotonic(e.Timestamp))
+		if rec.opts.IncludeBodies && int64(e.EncodedDataLength) <= rec.opts.MaxBodyBytes {
+			rec.bodies.Add(1)
+			go b.captureHARBody(t, rec, p, e.RequestID)
+		}
+
+	case *network.EventLoadingFailed:
+		p, ok := rec.pending[e.RequestID]
+		if !ok {
+			return
+		}
+		delete(rec.pending, e.RequestID)
+		p.entry.Comment = e.ErrorText
+		p.finish(monotonic(e.Timestamp))
+	}
+}
+
+func (b *Browser) captureHARBody(t *tab, rec *harRecorder, p *harPending, id network.RequestID) {
+	defer rec.bodies.Done()
+	var body []byte
+	err := chromedp.Run(t.ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		var err error
+		body, err = network.GetResponseBody(id).Do(ctx)
+		return err
+	}))
+	if err != nil || int64(len(body)) > rec.opts.MaxBodyBytes {
+		return
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	content := &p.entry.Response.Content
+	content.Size = int64(len(body))
+	if utf8.Valid(body) {
+		content.Text = string(body)
+	} else {
+		content.Text = base64.StdEncoding.EncodeToString(body)
+		content.Encoding = "base64"
+	}
+}
+
+func (p *harPending) applyResponse(resp *network.Response) {
+	r := &p.entry.Response
+	r.Status = resp.Status
+	r.StatusText = resp.StatusText
+	r.HTTPVersion = harHTTPVersion(resp.Protocol)
+	r.Headers = harHeaders(resp.Headers)
+	r.RedirectURL = headerValue(resp.Headers, "Location")
+	r.HeadersSize = -1
+	r.BodySize = -1
+	r.Content = HARContent{MimeType: resp.MimeType}
+	if len(resp.RequestHeaders) > 0 {
+		p.entry.Request.Headers = harHeaders(resp.RequestHeaders)
+	}
+	p.entry.Request.HTTPVersion = r.HTTPVersion
+	p.entry.ServerIPAddress = resp.RemoteIPAddress
+	if resp.ConnectionID > 0 {
+		p.entry.Connection = fmt.Sprintf("%.0f", resp.ConnectionID)
+	}
+	p.timing = resp.Timing
+}
+
+// finish derives the entry's total time and phase timings. Chrome reports
+// phases as millisecond offsets from timing.RequestTime, with -1 for phases
+// that did not happen.
+func (p *harPending) finish(end time.Time) {
+	total := float64(end.Sub(p.start)) / float64(time.Millisecond)
+	if total < 0 {
+		total = 0
+	}
+	p.entry.Time = total
+
+	timing := p.timing
+	if timing == nil {
+		p.entry.Timings.Receive = total
+		return
+	}
+	t := &p.entry.Timings
+	for _, start := range []float64{timing.DNSStart, timing.ConnectStart, timing.SendStart} {
+		if start >= 0 {
+			t.Blocked = start
+			break
+		}
+	}
+	if timing.DNSStart >= 0 {
+		t.DNS = timing.DNSEnd - timing.DNSStart
+	}
+	if timing.ConnectStart >= 0 {
+		t.Connect = timing.ConnectEnd - timing.ConnectStart
+	}
+	if timing.SslStart >= 0 {
+		t.SSL = timing.SslEnd - timing.SslStart
+	}
+	t.Send = timing.SendEnd - timing.SendStart
+	t.Wait = timing.ReceiveHeadersEnd - timing.SendEnd
+	elapsed := t.Send + t.Wait
+	for _, phase := range []float64{t.Blocked, t.DNS, t.Connect} {
+		if phase > 0 {
+			elapsed += phase
+		}
+	}
+	t.Receive = total - elapsed
+	if t.Receive < 0 {
+		t.Receive = 0
+	}
+}
+
+func harRequest(req *network.Request) HARRequest {
+	result := HARRequest{
+		Method:      req.Method,
+		URL:         req.URL + req.URLFragment,
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []HARNameValue{},
+		Headers:     harHeaders(req.Headers),
+		QueryString: []HARNameValue{},
+		HeadersSize: -1,
+	}
+	if parsed, err := url.Parse(req.URL); err == nil {
+		for name, values := range parsed.Query() {
+			for _, value := range values {
+				result.QueryString = append(result.QueryString, HARNameValue{Name: name, Value: value})
+			}
+		}
+	}
+	if req.HasPostData {
+		var body strings.Builder
+		for _, entry := range req.PostDataEntries {
+			if decoded, err := base64.StdEncoding.DecodeString(entry.Bytes); err == nil {
+				body.Write(decoded)
+			}
+		}
+		result.PostData = &HARPostData{
+			MimeType: headerValue(req.Headers, "Content-Type"),
+			Text:     body.String(),
+		}
+		result.BodySize = int64(body.Len())
+	}
+	return result
+}
+
+func harHeaders(headers network.Headers) []HARNameValue {
+	result := make([]HARNameValue, 0, len(headers))
+	for name, value := range headers {
+		result = append(result, HARNameValue{Name: name, Value: fmt.Sprint(value)})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+func headerValue(headers network.Headers, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return fmt.Sprint(value)
+		}
+	}
+	return ""
+}
+
+func harHTTPVersion(protocol string) string {
+	switch strings.ToLower(protocol) {
+	case "h2":
+		return "HTTP/2"
+	case "h3":
+		return "HTTP/3"
+	case "", "http/1.1":
+		return "HTTP/1.1"
+	default:
+		return strings.ToUpper(protocol)
+	}
+}
+
+func monotonic(ts *cdp.MonotonicTime) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.Time()
+}
+
+func wallTime(e *network.EventRequestWillBeSent) time.Time {
+	if e.WallTime == nil {
+		return time.Now().UTC()
+	}
+	return e.WallTime.Time().UTC()
+}
@@ -0,0 +1,165 @@
+package browser
+
+import (
+	"encoding/base64"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestHARRequest(t *testing.T) {
+	encode := func(s string) *network.PostDataEntry {
+		return &network.PostDataEntry{Bytes: base64.StdEncoding.EncodeToString([]byte(s))}
+	}
+	tests := []struct {
+		name string
+		req  *network.Request
+		want HARRequest
+	}{
+		{
+			name: "get with query and fragment",
+			req: &network.Request{
+				Method:      "GET",
+				URL:         "https://example.com/search?q=go&page=2&q=chromedp",
+				URLFragment: "#results",
+				Headers:     network.Headers{"User-Agent": "test", "Accept": "*/*"},
+			},
+			want: HARRequest{
+				Method:      "GET",
+				URL:         "https://example.com/search?q=go&page=2&q=chromedp#results",
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []HARNameValue{},
+				Headers:     []HARNameValue{{Name: "Accept", Value: "*/*"}, {Name: "User-Agent", Value: "test"}},
+				QueryString: []HARNameValue{{Name: "page", Value: "2"}, {Name: "q", Value: "chromedp"}, {Name: "q", Value: "go"}},
+				HeadersSize: -1,
+			},
+		},
+		{
+			name: "post body from entries",
+			req: &network.Request{
+				Method:          "POST",
+				URL:             "https://example.com/api",
+				Headers:         network.Headers{"content-type": "application/json"},
+				HasPostData:     true,
+				PostDataEntries: []*network.PostDataEntry{encode(`{"a":`), encode(`1}`), {Bytes: "not base64!"}},
+			},
+			want: HARRequest{
+				Method:      "POST",
+				URL:         "https://example.com/api",
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []HARNameValue{},
+				Headers:     []HARNameValue{{Name: "content-type", Value: "application/json"}},
+				QueryString: []HARNameValue{},
+				PostData:    &HARPostData{MimeType: "application/json", Text: `{"a":1}`},
+				HeadersSize: -1,
+				BodySize:    7,
+			},
+		},
+		{
+			name: "post without entries",
+			req:  &network.Request{Method: "POST", URL: "https://example.com/", HasPostData: true},
+			want: HARRequest{
+				Method:      "POST",
+				URL:         "https://example.com/",
+				HTTPVersion: "HTTP/1.1",
+				Cookies:     []HARNameValue{},
+				Headers:     []HARNameValue{},
+				QueryString: []HARNameValue{},
+				PostData:    &HARPostData{},
+				HeadersSize: -1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := harRequest(tt.req)
+			// Query parameters come out of a map.
+			sort.SliceStable(got.QueryString, func(i, j int) bool {
+				a, b := got.QueryString[i], got.QueryString[j]
+				return a.Name < b.Name || a.Name == b.Name && a.Value < b.Value
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("harRequest() =\n%+v\nwant\n%+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHARPendingFinish(t *testing.T) {
+	start := time.Unix(1000, 0)
+	unset := HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1}
+	tests := []struct {
+		name     string
+		elapsed  time.Duration
+		timing   *network.ResourceTiming
+		wantTime float64
+		want     HARTimings
+	}{
+		{
+			name:     "no timing is all receive",
+			elapsed:  120 * time.Millisecond,
+			wantTime: 120,
+			want:     HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Receive: 120},
+		},
+		{
+			name:    "fresh tls connection",
+			elapsed: 200 * time.Millisecond,
+			timing: &network.ResourceTiming{
+				DNSStart: 5, DNSEnd: 15,
+				ConnectStart: 15, ConnectEnd: 60,
+				SslStart: 30, SslEnd: 60,
+				SendStart: 62, SendEnd: 64,
+				ReceiveHeadersEnd: 150,
+			},
+			wantTime: 200,
+			// receive = 200 - (5 blocked + 10 dns + 45 connect + 2 send + 86 wait)
+			want: HARTimings{Blocked: 5, DNS: 10, Connect: 45, SSL: 30, Send: 2, Wait: 86, Receive: 52},
+		},
+		{
+			name:    "reused connection",
+			elapsed: 50 * time.Millisecond,
+			timing: &network.ResourceTiming{
+				DNSStart: -1, DNSEnd: -1,
+				ConnectStart: -1, ConnectEnd: -1,
+				SslStart: -1, SslEnd: -1,
+				SendStart: 1, SendEnd: 2,
+				ReceiveHeadersEnd: 40,
+			},
+			wantTime: 50,
+			want:     HARTimings{Blocked: 1, DNS: -1, Connect: -1, SSL: -1, Send: 1, Wait: 38, Receive: 10},
+		},
+		{
+			name:    "receive never negative",
+			elapsed: 10 * time.Millisecond,
+			timing: &network.ResourceTiming{
+				DNSStart: -1, ConnectStart: -1, SslStart: -1,
+				SendStart: 0, SendEnd: 1,
+				ReceiveHeadersEnd: 30,
+			},
+			wantTime: 10,
+			want:     HARTimings{Blocked: 0, DNS: -1, Connect: -1, SSL: -1, Send: 1, Wait: 29, Receive: 0},
+		},
+		{
+			name:     "end before start",
+			elapsed:  -time.Second,
+			wantTime: 0,
+			want:     HARTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1, Receive: 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &harPending{start: start, timing: tt.timing}
+			p.entry.Timings = unset
+			p.finish(start.Add(tt.elapsed))
+			if p.entry.Time != tt.wantTime {
+				t.Errorf("time = %v, want %v", p.entry.Time, tt.wantTime)
+			}
+			if p.entry.Timings != tt.want {
+				t.Errorf("timings = %+v, want %+v", p.entry.Timings, tt.want)
+			}
+		})
+	}
+}
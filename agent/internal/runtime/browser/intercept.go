@@ -0,0 +1,455 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Route actions understood by RouteRule.Action.
+const (
+	RouteBlock    = "block"
+	RouteFulfill  = "fulfill"
+	RouteModify   = "modify"
+	RouteContinue = "continue"
+)
+
+// ErrUnknownRoute is returned when a route rule ID does not exist on the
+// target.
+var ErrUnknownRoute = errors.New("browser: unknown route")
+
+// RouteRule intercepts requests whose URL matches a glob ('*' matches any run
+// of characters, '?' exactly one) and decides their fate. Rules are evaluated
+// in registration order; the first match wins and unmatched requests continue
+// untouched.
+type RouteRule struct {
+	ID           string            `json:"id"`
+	URLPattern   string            `json:"url_pattern"`
+	Method       string            `json:"method,omitempty"`
+	ResourceType string            `json:"resource_type,omitempty"`
+	Action       string            `json:"action"`
+	Status       int               `json:"status,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	Body         string            `json:"body,omitempty"`
+	BodyBase64   bool              `json:"body_base64,omitempty"`
+	ErrorReason  string            `json:"error_reason,omitempty"`
+	Times        int               `json:"times,omitempty"`
+	Hits         int               `json:"hits"`
+	CreatedAt    time.Time         `json:"created_at"`
+
+	pattern *regexp.Regexp
+}
+
+// interceptor owns the Fetch domain for one tab. Every feature that pauses
+// requests goes through it so a single requestPaused handler sees them all.
+type interceptor struct {
+	mu        sync.Mutex
+	rules     []*RouteRule
+	auth      *Credentials
+	block     *blockRules
+	answered  map[challengeKey]bool
+	enabled   bool
+	listening bool
+}
+
+// challengeKey identifies an auth challenge. A request can be challenged by
+// its proxy and then by the server, and each must be answered once.
+type challengeKey struct {
+	request fetch.RequestID
+	source  fetch.AuthChallengeSource
+}
+
+// maxAnswered bounds the record of answered auth challenges; it only has to
+// outlive the retry of a single request.
+const maxAnswered = 256
+
+func newInterceptor() *interceptor {
+	return &interceptor{answered: make(map[challengeKey]bool)}
+}
+
+// globPattern compiles a URL glob into an anchored regular expression.
+// A backslash escapes the next character.
+func globPattern(glob string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+	escaped := false
+	for _, r := range glob {
+		switch {
+		case escaped:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '*':
+			expr.WriteString(".*")
+		case r == '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
+
+func (rule *RouteRule) validate() error {
+	if strings.TrimSpace(rule.URLPattern) == "" {
+		rule.URLPattern = "*"
+	}
+	pattern, err := globPattern(rule.URLPattern)
+	if err != nil {
+		return fmt.Errorf("browser: invalid url_pattern %q: %w", rule.URLPattern, err)
+	}
+	rule.pattern = pattern
+	rule.Method = strings.ToUpper(strings.TrimSpace(rule.Method))
+	rule.Action = strings.ToLower(strings.TrimSpace(rule.Action))
+
+	switch rule.Action {
+	case RouteBlock:
+		if rule.ErrorReason == "" {
+			rule.ErrorReason = network.ErrorReasonBlockedByClient.String()
+		}
+	case RouteFulfill:
+		if rule.Status == 0 {
+			rule.Status = 200
+		}
+		if rule.Status < 100 || rule.Status > 599 {
+			return fmt.Errorf("browser: invalid fulfil status %d", rule.Status)
+		}
+		if rule.BodyBase64 {
+			if _, err := base64.StdEncoding.DecodeString(rule.Body); err != nil {
+				return fmt.Errorf("browser: body is not valid base64: %w", err)
+			}
+		}
+	case RouteModify, RouteContinue:
+	default:
+		return fmt.Errorf("browser: unknown route action %q (want block, fulfill, modify or continue)", rule.Action)
+	}
+	if rule.Times < 0 {
+		return errors.New("browser: times must not be negative")
+	}
+	return nil
+}
+
+func (rule *RouteRule) matches(ev *fetch.EventRequestPaused) bool {
+	if rule.Method != "" && rule.Method != ev.Request.Method {
+		return false
+	}
+	if rule.ResourceType != "" && !strings.EqualFold(rule.ResourceType, ev.ResourceType.String()) {
+		return false
+	}
+	return rule.pattern.MatchString(ev.Request.URL)
+}
+
+// match returns a copy of the first rule matching ev and records the hit,
+// retiring rules that have used up their allowed number of matches.
+func (ic *interceptor) match(ev *fetch.EventRequestPaused) (RouteRule, bool) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	for i, rule := range ic.rules {
+		if !rule.matches(ev) {
+			continue
+		}
+		rule.Hits++
+		matched := *rule
+		if rule.Times > 0 && rule.Hits >= rule.Times {
+			ic.rules = append(ic.rules[:i], ic.rules[i+1:]...)
+		}
+		return matched, true
+	}
+	return RouteRule{}, false
+}
+
+// needsFetch reports whether the tab's own rules require paused requests.
+func (ic *interceptor) needsFetch() bool {
+	return len(ic.rules) > 0 || ic.auth != nil || !ic.block.empty()
+}
+
+// setAuth overrides the session credentials on this tab; nil removes the
+// override.
+func (ic *interceptor) setAuth(creds *Credentials) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	ic.auth = creds
+}
+
+// setBlock adds a block policy on top of the session's on this tab; nil
+// removes it.
+func (ic *interceptor) setBlock(rules *blockRules) {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	ic.block = rules
+}
+
+func (ic *interceptor) blockRules() *blockRules {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	return ic.block
+}
+
+// firstAnswer records that a challenge from source for the request has been
+// answered and reports whether this is the first time. A second challenge
+// from the same source for the same request means the credentials were
+// rejected.
+func (ic *interceptor) firstAnswer(id fetch.RequestID, source fetch.AuthChallengeSource) bool {
+	key := challengeKey{request: id, source: source}
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	if ic.answered[key] {
+		delete(ic.answered, key)
+		return false
+	}
+	if len(ic.answered) >= maxAnswered {
+		ic.answered = make(map[challengeKey]bool)
+	}
+	ic.answered[key] = true
+	return true
+}
+
+// syncFetch enables or disables the Fetch domain on the tab to match the
+// interceptor's configuration. It must run on the tab's action queue.
+func (b *Browser) syncFetch(ctx context.Context, t *tab) error {
+	ic := t.intercept
+	sp := b.policy(t.session)
+	sessionNeeds := sp.needsFetch()
+	handleAuth := sp.credentials() != nil || sp.proxyCredentials() != nil
+	ic.mu.Lock()
+	needed := ic.needsFetch() || sessionNeeds
+	handleAuth = handleAuth || ic.auth != nil
+	startListener := needed && !ic.listening
+	if startListener {
+		ic.listening = true
+	}
+	ic.mu.Unlock()
+
+	if startListener {
+		chromedp.ListenTarget(t.ctx, func(ev any) {
+			switch ev := ev.(type) {
+			case *fetch.EventRequestPaused:
+				go b.handlePaused(t, ev)
+			case *fetch.EventAuthRequired:
+				go b.handleAuth(t, ev)
+			}
+		})
+	}
+
+	var err error
+	if needed {
+		err = chromedp.Run(ctx, fetch.Enable().
+			WithPatterns([]*fetch.RequestPattern{{URLPattern: "*"}}).
+			WithHandleAuthRequests(handleAuth))
+	} else {
+		ic.mu.Lock()
+		enabled := ic.enabled
+		ic.mu.Unlock()
+		if !enabled {
+			return nil
+		}
+		err = chromedp.Run(ctx, fetch.Disable())
+	}
+	if err != nil {
+		return err
+	}
+	ic.mu.Lock()
+	ic.enabled = needed
+	ic.mu.Unlock()
+	return nil
+}
+
+// handlePaused resolves a paused request. It runs outside the action queue:
+// the page is blocked on the answer, and an action waiting on the same page
+// would otherwise deadlock.
+func (b *Browser) handlePaused(t *tab, ev *fetch.EventRequestPaused) {
+	var action chromedp.Action = fetch.ContinueRequest(ev.RequestID)
+	if rule, ok := t.intercept.match(ev); ok {
+		switch rule.Action {
+		case RouteBlock:
+			action = fetch.FailRequest(ev.RequestID, network.ErrorReason(rule.ErrorReason))
+			b.publish("agent", fmt.Sprintf("route %s blocked %s", rule.ID, ev.Request.URL))
+		case RouteFulfill:
+			body := rule.Body
+			if !rule.BodyBase64 {
+				body = base64.StdEncoding.EncodeToString([]byte(body))
+			}
+			action = fetch.FulfillRequest(ev.RequestID, int64(rule.Status)).
+				WithResponseHeaders(headerEntries(rule.Headers)).
+				WithBody(body)
+			b.publish("agent", fmt.Sprintf("route %s fulfilled %s with %d", rule.ID, ev.Request.URL, rule.Status))
+		case RouteModify:
+			action = fetch.ContinueRequest(ev.RequestID).
+				WithHeaders(mergeHeaders(ev.Request.Headers, rule.Headers))
+		}
+	} else if b.policy(t.session).blocks(ev, t.intercept.blockRules()) {
+		action = fetch.FailRequest(ev.RequestID, network.ErrorReasonBlockedByClient)
+	}
+	if err := chromedp.Run(t.ctx, action); err != nil && t.ctx.Err() == nil {
+		b.publish("agent", fmt.Sprintf("intercept %s failed: %v", ev.Request.URL, err))
+	}
+}
+
+// handleAuth answers an authentication challenge. Proxy challenges get the
+// session's proxy credentials; server challenges get the tab's credentials,
+// falling back to the session's. Credentials are offered once per request;
+// if the challenge repeats the request is cancelled rather than looping.
+func (b *Browser) handleAuth(t *tab, ev *fetch.EventAuthRequired) {
+	var creds *Credentials
+	source := fetch.AuthChallengeSourceServer
+	if ev.AuthChallenge != nil && ev.AuthChallenge.Source != "" {
+		source = ev.AuthChallenge.Source
+	}
+	if source == fetch.AuthChallengeSourceProxy {
+		creds = b.policy(t.session).proxyCredentials()
+	} else {
+		t.intercept.mu.Lock()
+		creds = t.intercept.auth
+		t.intercept.mu.Unlock()
+		if creds == nil {
+			creds = b.policy(t.session).credentials()
+		}
+	}
+
+	response := &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseDefault}
+	if creds != nil {
+		if t.intercept.firstAnswer(ev.RequestID, source) {
+			response = &fetch.AuthChallengeResponse{
+				Response: fetch.AuthChallengeResponseResponseProvideCredentials,
+				Username: creds.Username,
+				Password: creds.Password,
+			}
+		} else {
+			response = &fetch.AuthChallengeResponse{Response: fetch.AuthChallengeResponseResponseCancelAuth}
+			origin := ev.Request.URL
+			if ev.AuthChallenge != nil {
+				origin = ev.AuthChallenge.Origin
+			}
+			b.publish("agent", fmt.Sprintf("credentials for %s rejected by %s", creds.Username, origin))
+		}
+	}
+	if err := chromedp.Run(t.ctx, fetch.ContinueWithAuth(ev.RequestID, response)); err != nil && t.ctx.Err() == nil {
+		b.publish("agent", fmt.Sprintf("auth %s failed: %v", ev.Request.URL, err))
+	}
+}
+
+func headerEntries(headers map[string]string) []*fetch.HeaderEntry {
+	entries := make([]*fetch.HeaderEntry, 0, len(headers))
+	for name, value := range headers {
+		entries = append(entries, &fetch.HeaderEntry{Name: name, Value: value})
+	}
+	return entries
+}
+
+// mergeHeaders applies overrides to the original request headers. Header
+// names compare case-insensitively and an empty override removes the header.
+func mergeHeaders(original network.Headers, overrides map[string]string) []*fetch.HeaderEntry {
+	merged := make(map[string]*fetch.HeaderEntry, len(original)+len(overrides))
+	for name, value := range original {
+		merged[strings.ToLower(name)] = &fetch.HeaderEntry{Name: name, Value: fmt.Sprint(value)}
+	}
+	for name, value := range overrides {
+		if value == "" {
+			delete(merged, strings.ToLower(name))
+			continue
+		}
+		merged[strings.ToLower(name)] = &fetch.HeaderEntry{Name: name, Value: value}
+	}
+	entries := make([]*fetch.HeaderEntry, 0, len(merged))
+	for _, entry := range merged {
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// AddRoute registers an interception rule on the target.
+func (b *Browser) AddRoute(targetID string, timeout time.Duration, rule RouteRule) (RouteRule, error) {
+	if err := rule.validate(); err != nil {
+		return RouteRule{}, err
+	}
+	id, err := newID()
+	if err != nil {
+		return RouteRule{}, fmt.Errorf("browser: route id: %w", err)
+	}
+	rule.ID = id
+	rule.Hits = 0
+	rule.CreatedAt = time.Now().UTC()
+
+	t, err := b.tab(targetID)
+	if err != nil {
+		return RouteRule{}, err
+	}
+	err = b.run(targetID, timeout, "route_add", fmt.Sprintf("Adding %s route for %s", rule.Action, rule.URLPattern), func(ctx context.Context) (string, error) {
+		stored := rule
+		t.intercept.mu.Lock()
+		t.intercept.rules = append(t.intercept.rules, &stored)
+		t.intercept.mu.Unlock()
+		if err := b.syncFetch(ctx, t); err != nil {
+			t.intercept.removeRule(rule.ID)
+			return "", err
+		}
+		return fmt.Sprintf("route %s added", rule.ID), nil
+	})
+	if err != nil {
+		return RouteRule{}, err
+	}
+	return rule, nil
+}
+
+// Routes lists the target's active interception rules.
+func (b *Browser) Routes(targetID string) ([]RouteRule, error) {
+	t, err := b.tab(targetID)
+	if err != nil {
+		return nil, err
+	}
+	t.intercept.mu.Lock()
+	defer t.intercept.mu.Unlock()
+	rules := make([]RouteRule, 0, len(t.intercept.rules))
+	for _, rule := range t.intercept.rules {
+		rules = append(rules, *rule)
+	}
+	return rules, nil
+}
+
+// DeleteRoute removes one rule from the target. An empty id removes them all.
+func (b *Browser) DeleteRoute(targetID string, timeout time.Duration, id string) error {
+	t, err := b.tab(targetID)
+	if err != nil {
+		return err
+	}
+	name, line := "route_delete", fmt.Sprintf("Removing route %s", id)
+	if id == "" {
+		name, line = "route_clear", "Removing all routes"
+	}
+	return b.run(targetID, timeout, name, line, func(ctx context.Context) (string, error) {
+		if id == "" {
+			t.intercept.mu.Lock()
+			t.intercept.rules = nil
+			t.intercept.mu.Unlock()
+		} else if !t.intercept.removeRule(id) {
+			return "", ErrUnknownRoute
+		}
+		if err := b.syncFetch(ctx, t); err != nil {
+			return "", err
+		}
+		return "", nil
+	})
+}
+
+func (ic *interceptor) removeRule(id string) bool {
+	ic.mu.Lock()
+	defer ic.mu.Unlock()
+	for i, rule := range ic.rules {
+		if rule.ID == id {
+			ic.rules = append(ic.rules[:i], ic.rules[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package browser
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestGlobPattern(t *testing.T) {
+	tests := []struct {
+		glob  string
+		url   string
+		match bool
+	}{
+		{"*", "https://example.com/", true},
+		{"*", "", true},
+		{"https://example.com/*", "https://example.com/api/items?page=2", true},
+		{"https://example.com/*", "https://example.org/", false},
+		{"*.png", "https://cdn.example.com/logo.png", true},
+		{"*.png", "https://cdn.example.com/logo.png?v=1", false},
+		{"*/api/v?/*", "https://example.com/api/v2/items", true},
+		{"*/api/v?/*", "https://example.com/api/v10/items", false},
+		{"https://example.com/a", "https://example.com/ab", false},
+		{"https://example.com/a", "xhttps://example.com/a", false},
+		// Regexp metacharacters are literal.
+		{"*.example.com/(x)+", "https://www.example.com/(x)+", true},
+		{"*.example.com/*", "https://wwwXexample.com/", false},
+		{"*?q=[1]", "https://example.com/?q=[1]", true},
+		// A backslash escapes the next character.
+		{`*/literal\*`, "https://example.com/literal*", true},
+		{`*/literal\*`, "https://example.com/literally", false},
+		{`*\?x`, "https://example.com/?x", true},
+		{`*\?x`, "https://example.com/ax", false},
+		{`*\\*`, `https://example.com/a\b`, true},
+	}
+	for _, tt := range tests {
+		pattern, err := globPattern(tt.glob)
+		if err != nil {
+			t.Fatalf("globPattern(%q): %v", tt.glob, err)
+		}
+		if got := pattern.MatchString(tt.url); got != tt.match {
+			t.Errorf("globPattern(%q) matches %q = %v, want %v", tt.glob, tt.url, got, tt.match)
+		}
+	}
+}
+
+func TestHandleAuthWithoutChallenge(t *testing.T) {
+	b := &Browser{log: newLogEmitter(), policies: make(map[string]*sessionPolicy)}
+	defer b.log.Close()
+	events, unsubscribe := b.log.Subscribe(8)
+	defer unsubscribe()
+
+	tb := &tab{ctx: context.Background(), intercept: newInterceptor()}
+	tb.intercept.auth = &Credentials{Username: "alice", Password: "secret"}
+	ev := &fetch.EventAuthRequired{
+		RequestID: "r1",
+		Request:   &network.Request{URL: "https://example.com/private"},
+	}
+	// The second challenge for the same request is a rejection.
+	b.handleAuth(tb, ev)
+	b.handleAuth(tb, ev)
+
+	want := "credentials for alice rejected by https://example.com/private"
+	for {
+		select {
+		case event := <-events:
+			if strings.Contains(event.Line, want) {
+				return
+			}
+		default:
+			t.Fatalf("no %q log line", want)
+		}
+	}
+}
+
+func TestHandleAuthProxyThenServer(t *testing.T) {
+	b := &Browser{log: newLogEmitter(), policies: make(map[string]*sessionPolicy)}
+	defer b.log.Close()
+	events, unsubscribe := b.log.Subscribe(16)
+	defer unsubscribe()
+	b.policy("").setProxyAuth(&Credentials{Username: "proxyuser", Password: "p"})
+
+	tb := &tab{ctx: context.Background(), intercept: newInterceptor()}
+	tb.intercept.auth = &Credentials{Username: "alice", Password: "secret"}
+	challenge := func(source fetch.AuthChallengeSource, origin string) *fetch.EventAuthRequired {
+		return &fetch.EventAuthRequired{
+			RequestID:     "r1",
+			Request:       &network.Request{URL: "https://example.com/private"},
+			AuthChallenge: &fetch.AuthChallenge{Source: source, Origin: origin},
+		}
+	}
+	rejected := func() []string {
+		var lines []string
+		for {
+			select {
+			case event := <-events:
+				if strings.Contains(event.Line, "rejected") {
+					lines = append(lines, event.Line)
+				}
+			default:
+				return lines
+			}
+		}
+	}
+
+	// The proxy and then the server challenge the same request; each gets
+	// its own credentials.
+	b.handleAuth(tb, challenge(fetch.AuthChallengeSourceProxy, "http://proxy.local:3128"))
+	b.handleAuth(tb, challenge(fetch.AuthChallengeSourceServer, "https://example.com"))
+	if lines := rejected(); len(lines) != 0 {
+		t.Fatalf("first challenges from each source were rejected: %v", lines)
+	}
+
+	b.handleAuth(tb, challenge(fetch.AuthChallengeSourceServer, "https://example.com"))
+	lines := rejected()
+	if len(lines) != 1 || !strings.Contains(lines[0], "credentials for alice rejected by https://example.com") {
+		t.Fatalf("repeated server challenge logged %v, want alice rejected", lines)
+	}
+}
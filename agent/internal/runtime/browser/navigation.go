@@ -0,0 +1,377 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+// Conditions a navigation can wait for.
+const (
+	WaitCommit            = "commit"
+	WaitDOMContentLoaded  = "domcontentloaded"
+	WaitLoad              = "load"
+	WaitNetworkIdle       = "networkidle"
+	WaitNetworkAlmostIdle = "networkalmostidle"
+)
+
+// networkIdleWindow is how long the in-flight request count has to stay at or
+// under its threshold before the network counts as idle. It matches the
+// window Chrome uses for its own networkIdle lifecycle events.
+const networkIdleWindow = 500 * time.Millisecond
+
+// ErrHTTPStatus is returned when a navigation made with FailOnStatus ends on
+// a 4xx or 5xx document.
+var ErrHTTPStatus = errors.New("browser: navigation returned an error status")
+
+// NavigationResult describes the main document a navigation ended on.
+type NavigationResult struct {
+	URL        string            `json:"url"`
+	Status     int64             `json:"status"`
+	StatusText string            `json:"status_text"`
+	Headers    map[string]string `json:"headers"`
+	MimeType   string            `json:"mime_type"`
+	Title      string            `json:"title"`
+	Redirects  []Redirect        `json:"redirects"`
+	Timing     NavigationTiming  `json:"timing"`
+}
+
+// Redirect is one HTTP redirect hop of a navigation.
+type Redirect struct {
+	URL      string `json:"url"`
+	Status   int64  `json:"status"`
+	Location string `json:"location"`
+}
+
+// NavigationTiming holds milliseconds elapsed since the first document
+// request was sent. Milestones that were not reached are zero.
+type NavigationTiming struct {
+	StartedAt          time.Time `json:"started_at"`
+	ResponseMs         float64   `json:"response_ms"`
+	DOMContentLoadedMs float64   `json:"dom_content_loaded_ms"`
+	LoadMs             float64   `json:"load_ms"`
+	TotalMs            float64   `json:"total_ms"`
+}
+
+// navDocument collects the events of one main-frame document load.
+type navDocument struct {
+	start      time.Time
+	wallStart  time.Time
+	redirects  []Redirect
+	response   *network.Response
+	responseAt time.Time
+	failure    string
+	lifecycle  map[string]time.Time
+}
+
+// navTracker follows main-frame document loads on a tab. Events are buffered
+// per loader so the navigation can be adopted once its loader ID is known.
+// It also counts the tab's in-flight requests to detect network idleness.
+type navTracker struct {
+	mu        sync.Mutex
+	frameID   cdp.FrameID
+	docs      map[cdp.LoaderID]*navDocument
+	byRequest map[network.RequestID]*navDocument
+	changed   chan struct{}
+
+	// latest is the loader of the most recent main-frame document request;
+	// withinDocument is set when the main frame navigated without one.
+	latest         cdp.LoaderID
+	withinDocument bool
+
+	inflight     map[network.RequestID]bool
+	idleAt       time.Time // zero while any request is in flight
+	almostIdleAt time.Time // zero while more than two are
+}
+
+// parseWaitUntil validates a wait condition, defaulting to load.
+func parseWaitUntil(until string) (string, error) {
+	switch until = strings.ToLower(strings.TrimSpace(until)); until {
+	case "":
+		return WaitLoad, nil
+	case WaitCommit, WaitDOMContentLoaded, WaitLoad, WaitNetworkIdle, WaitNetworkAlmostIdle:
+		return until, nil
+	}
+	return "", fmt.Errorf("browser: unknown wait_until %q (want commit, domcontentloaded, load, networkidle or networkalmostidle)", until)
+}
+
+// trackNavigation starts following the main frame's document loads until
+// ctx is cancelled.
+func trackNavigation(ctx context.Context) (*navTracker, error) {
+	tree, err := page.GetFrameTree().Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("browser: frame tree: %w", err)
+	}
+	now := time.Now()
+	nt := &navTracker{
+		frameID:      tree.Frame.ID,
+		docs:         make(map[cdp.LoaderID]*navDocument),
+		byRequest:    make(map[network.RequestID]*navDocument),
+		changed:      make(chan struct{}, 1),
+		inflight:     make(map[network.RequestID]bool),
+		idleAt:       now,
+		almostIdleAt: now,
+	}
+	chromedp.ListenTarget(ctx, nt.handle)
+	return nt, nil
+}
+
+func (nt *navTracker) doc(loaderID cdp.LoaderID) *navDocument {
+	d, ok := nt.docs[loaderID]
+	if !ok {
+		d = &navDocument{lifecycle: make(map[string]time.Time)}
+		nt.docs[loaderID] = d
+	}
+	return d
+}
+
+func (nt *navTracker) handle(ev any) {
+	nt.mu.Lock()
+	defer nt.mu.Unlock()
+	switch e := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		nt.inflight[e.RequestID] = true
+		nt.countInflight()
+		if e.FrameID != nt.frameID || e.Type != network.ResourceTypeDocument {
+			break
+		}
+		d := nt.doc(e.LoaderID)
+		nt.latest = e.LoaderID
+		if e.RedirectResponse != nil {
+			d.redirects = append(d.redirects, Redirect{
+				URL:      e.RedirectResponse.URL,
+				Status:   e.RedirectResponse.Status,
+				Location: e.Request.URL,
+			})
+		} else {
+			d.start = monotonic(e.Timestamp)
+			d.wallStart = wallTime(e)
+		}
+		nt.byRequest[e.RequestID] = d
+	case *network.EventResponseReceived:
+		d, ok := nt.byRequest[e.RequestID]
+		if !ok {
+			return
+		}
+		d.response = e.Response
+		d.responseAt = monotonic(e.Timestamp)
+	case *network.EventLoadingFinished:
+		delete(nt.inflight, e.RequestID)
+		nt.countInflight()
+	case *network.EventLoadingFailed:
+		delete(nt.inflight, e.RequestID)
+		nt.countInflight()
+		if d, ok := nt.byRequest[e.RequestID]; ok {
+			d.failure = e.ErrorText
+		}
+	case *page.EventNavigatedWithinDocument:
+		if e.FrameID == nt.frameID {
+			nt.withinDocument = true
+		}
+	case *page.EventFrameNavigated:
+		// A back/forward cache restore shows a document without loading it.
+		if e.Frame.ParentID == "" && e.Type == page.NavigationTypeBackForwardCacheRestore {
+			nt.withinDocument = true
+		}
+	case *page.EventLifecycleEvent:
+		if e.FrameID != nt.frameID {
+			return
+		}
+		nt.doc(e.LoaderID).lifecycle[e.Name] = monotonic(e.Timestamp)
+		// A document requested before the tracker started is adopted when
+		// it commits.
+		if e.Name == "init" && nt.latest == "" {
+			nt.latest = e.LoaderID
+		}
+	default:
+		return
+	}
+	select {
+	case nt.changed <- struct{}{}:
+	default:
+	}
+}
+
+// countInflight updates the idle timestamps after the in-flight set changed.
+func (nt *navTracker) countInflight() {
+	now := time.Now()
+	switch n := len(nt.inflight); {
+	case n == 0:
+		if nt.idleAt.IsZero() {
+			nt.idleAt = now
+		}
+		if nt.almostIdleAt.IsZero() {
+			nt.almostIdleAt = now
+		}
+	case n <= 2:
+		nt.idleAt = time.Time{}
+		if nt.almostIdleAt.IsZero() {
+			nt.almostIdleAt = now
+		}
+	default:
+		nt.idleAt = time.Time{}
+		nt.almostIdleAt = time.Time{}
+	}
+}
+
+// next blocks until the main frame starts navigating and returns the new
+// document's loader, or an empty loader when the navigation stayed within
+// the current document.
+func (nt *navTracker) next(ctx context.Context) (cdp.LoaderID, error) {
+	for {
+		nt.mu.Lock()
+		latest, within := nt.latest, nt.withinDocument
+		nt.mu.Unlock()
+		switch {
+		case latest != "":
+			return latest, nil
+		case within:
+			return "", nil
+		}
+		select {
+		case <-nt.changed:
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+}
+
+// wait blocks until the loader's document satisfies the wait condition or
+// fails to load.
+func (nt *navTracker) wait(ctx context.Context, loaderID cdp.LoaderID, until string) error {
+	for {
+		nt.mu.Lock()
+		reached, recheck, err := nt.reached(loaderID, until)
+		nt.mu.Unlock()
+		if err != nil || reached {
+			return err
+		}
+		var timer <-chan time.Time
+		if recheck > 0 {
+			timer = time.After(recheck)
+		}
+		select {
+		case <-nt.changed:
+		case <-timer:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// reached reports whether the document satisfies the condition. When it
+// only lacks time for the network to count as idle, recheck says how long
+// is left.
+func (nt *navTracker) reached(loaderID cdp.LoaderID, until string) (bool, time.Duration, error) {
+	d := nt.doc(loaderID)
+	if d.failure != "" {
+		return false, 0, fmt.Errorf("browser: page load error %s", d.failure)
+	}
+	_, loaded := d.lifecycle["load"]
+	idle := func(event string, since time.Time) (bool, time.Duration, error) {
+		if _, ok := d.lifecycle[event]; ok {
+			return true, 0, nil
+		}
+		if !loaded || since.IsZero() {
+			return false, 0, nil
+		}
+		if remaining := networkIdleWindow - time.Since(since); remaining > 0 {
+			return false, remaining, nil
+		}
+		return true, 0, nil
+	}
+	switch until {
+	case WaitCommit:
+		_, committed := d.lifecycle["commit"]
+		return committed || d.response != nil, 0, nil
+	case WaitDOMContentLoaded:
+		_, ok := d.lifecycle["DOMContentLoaded"]
+		return ok, 0, nil
+	case WaitNetworkIdle:
+		return idle("networkIdle", nt.idleAt)
+	case WaitNetworkAlmostIdle:
+		return idle("networkAlmostIdle", nt.almostIdleAt)
+	default:
+		return loaded, 0, nil
+	}
+}
+
+// followNavigation runs start, which makes the main frame navigate, and waits
+// for the resulting document to satisfy until. start returns the loader when
+// the protocol reports one; otherwise the next navigation on the tab is
+// followed.
+func followNavigation(ctx context.Context, until string, start func(ctx context.Context) (cdp.LoaderID, bool, error)) (NavigationResult, error) {
+	var result NavigationResult
+	started := time.Now()
+	err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+		lctx, stop := context.WithCancel(ctx)
+		defer stop()
+		nt, err := trackNavigation(lctx)
+		if err != nil {
+			return err
+		}
+		loaderID, known, err := start(ctx)
+		if err != nil {
+			return err
+		}
+		if !known {
+			if loaderID, err = nt.next(ctx); err != nil {
+				return err
+			}
+		}
+		// Same-document navigations have no loader and nothing to wait for.
+		if loaderID != "" {
+			if err := nt.wait(ctx, loaderID, until); err != nil {
+				return err
+			}
+		}
+		result = nt.result(loaderID)
+		if result.URL == "" {
+			if err := chromedp.Location(&result.URL).Do(ctx); err != nil {
+				return err
+			}
+		}
+		return chromedp.Title(&result.Title).Do(ctx)
+	}))
+	result.Timing.TotalMs = float64(time.Since(started)) / float64(time.Millisecond)
+	return result, err
+}
+
+// result summarises the loader's document.
+func (nt *navTracker) result(loaderID cdp.LoaderID) NavigationResult {
+	nt.mu.Lock()
+	defer nt.mu.Unlock()
+	d := nt.doc(loaderID)
+	result := NavigationResult{
+		Headers:   map[string]string{},
+		Redirects: append([]Redirect{}, d.redirects...),
+		Timing:    NavigationTiming{StartedAt: d.wallStart},
+	}
+	if d.response != nil {
+		result.URL = d.response.URL
+		result.Status = d.response.Status
+		result.StatusText = d.response.StatusText
+		result.MimeType = d.response.MimeType
+		for name, value := range d.response.Headers {
+			result.Headers[name] = fmt.Sprint(value)
+		}
+	}
+	since := func(at time.Time) float64 {
+		if at.IsZero() || d.start.IsZero() {
+			return 0
+		}
+		return float64(at.Sub(d.start)) / float64(time.Millisecond)
+	}
+	result.Timing.ResponseMs = since(d.responseAt)
+	result.Timing.DOMContentLoadedMs = since(d.lifecycle["DOMContentLoaded"])
+	result.Timing.LoadMs = since(d.lifecycle["load"])
+	return result
+}
@@ -0,0 +1,326 @@
+package browser
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+)
+
+func TestParseWaitUntil(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", WaitLoad, false},
+		{"   ", WaitLoad, false},
+		{"load", WaitLoad, false},
+		{"commit", WaitCommit, false},
+		{"DOMContentLoaded", WaitDOMContentLoaded, false},
+		{" NetworkIdle ", WaitNetworkIdle, false},
+		{"networkalmostidle", WaitNetworkAlmostIdle, false},
+		{"networkidle0", "", true},
+		{"complete", "", true},
+	}
+	for _, tt := range tests {
+		got, err := parseWaitUntil(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseWaitUntil(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// navBase is the monotonic clock origin of the synthetic events below.
+var navBase = time.Unix(5000, 0)
+
+func navAt(ms int) *cdp.MonotonicTime {
+	ts := cdp.MonotonicTime(navBase.Add(time.Duration(ms) * time.Millisecond))
+	return &ts
+}
+
+func newTestNavTracker() *navTracker {
+	return &navTracker{
+		frameID:   "main",
+		docs:      make(map[cdp.LoaderID]*navDocument),
+		byRequest: make(map[network.RequestID]*navDocument),
+		changed:   make(chan struct{}, 1),
+		inflight:  make(map[network.RequestID]bool),
+	}
+}
+
+func documentRequest(id network.RequestID, loader cdp.LoaderID, url string, ms int) *network.EventRequestWillBeSent {
+	return &network.EventRequestWillBeSent{
+		RequestID: id,
+		LoaderID:  loader,
+		FrameID:   "main",
+		Type:      network.ResourceTypeDocument,
+		Request:   &network.Request{URL: url},
+		Timestamp: navAt(ms),
+	}
+}
+
+func lifecycle(loader cdp.LoaderID, name string, ms int) *page.EventLifecycleEvent {
+	return &page.EventLifecycleEvent{FrameID: "main", LoaderID: loader, Name: name, Timestamp: navAt(ms)}
+}
+
+func TestNavTrackerResult(t *testing.T) {
+	nt := newTestNavTracker()
+	redirect := documentRequest("doc", "L1", "https://example.com/home", 40)
+	redirect.RedirectResponse = &network.Response{URL: "http://example.com/", Status: 301}
+	for _, ev := range []any{
+		documentRequest("doc", "L1", "http://example.com/", 0),
+		redirect,
+		// Subresources and other frames do not touch the document.
+		&network.EventRequestWillBeSent{RequestID: "img", LoaderID: "L1", FrameID: "main", Type: network.ResourceTypeImage, Request: &network.Request{}, Timestamp: navAt(60)},
+		&network.EventRequestWillBeSent{RequestID: "child", LoaderID: "L9", FrameID: "ad", Type: network.ResourceTypeDocument, Request: &network.Request{}, Timestamp: navAt(60)},
+		&network.EventResponseReceived{RequestID: "doc", Timestamp: navAt(100), Response: &network.Response{
+			URL:        "https://example.com/home",
+			Status:     200,
+			StatusText: "OK",
+			MimeType:   "text/html",
+			Headers:    network.Headers{"Content-Length": 42},
+		}},
+		lifecycle("L1", "DOMContentLoaded", 250),
+		lifecycle("L1", "load", 400),
+	} {
+		nt.handle(ev)
+	}
+	if nt.latest != "L1" {
+		t.Fatalf("latest loader = %q, want L1", nt.latest)
+	}
+
+	got := nt.result("L1")
+	want := NavigationResult{
+		URL:        "https://example.com/home",
+		Status:     200,
+		StatusText: "OK",
+		MimeType:   "text/html",
+		Headers:    map[string]string{"Content-Length": "42"},
+		Redirects:  []Redirect{{URL: "http://example.com/", Status: 301, Location: "https://example.com/home"}},
+		Timing: NavigationTiming{
+			StartedAt:          got.Timing.StartedAt,
+			ResponseMs:         100,
+			DOMContentLoadedMs: 250,
+			LoadMs:             400,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("result() =\n%+v\nwant\n%+v", got, want)
+	}
+	if got.Timing.StartedAt.IsZero() {
+		t.Error("result() has no start time")
+	}
+	if n := len(nt.inflight); n != 3 {
+		t.Errorf("%d requests in flight, want 3", n)
+	}
+}
+
+func TestNavTrackerResultRedirectChain(t *testing.T) {
+	nt := newTestNavTracker()
+	started := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	first := documentRequest("doc", "L1", "http://example.com/", 10)
+	wall := cdp.TimeSinceEpoch(started)
+	first.WallTime = &wall
+	hop := func(from string, status int64, to string, ms int) *network.EventRequestWillBeSent {
+		ev := documentRequest("doc", "L1", to, ms)
+		ev.RedirectResponse = &network.Response{URL: from, Status: status}
+		return ev
+	}
+	responseAt := cdp.MonotonicTime(navBase.Add(10*time.Millisecond + 87500*time.Microsecond))
+	for _, ev := range []any{
+		first,
+		hop("http://example.com/", 301, "https://example.com/", 20),
+		hop("https://example.com/", 302, "https://example.com/home", 35),
+		hop("https://example.com/home", 307, "https://example.com/en/home", 50),
+		&network.EventResponseReceived{RequestID: "doc", Timestamp: &responseAt, Response: &network.Response{
+			URL:    "https://example.com/en/home",
+			Status: 200,
+			Headers: network.Headers{
+				"Content-Type":   "text/html; charset=utf-8",
+				"Content-Length": float64(1024),
+				"Set-Cookie":     "a=1\nb=2",
+				"X-Cache-Hit":    true,
+			},
+		}},
+		// Milestones of another document do not leak into this one.
+		lifecycle("L2", "load", 900),
+		lifecycle("L1", "DOMContentLoaded", 210),
+	} {
+		nt.handle(ev)
+	}
+
+	got := nt.result("L1")
+	wantRedirects := []Redirect{
+		{URL: "http://example.com/", Status: 301, Location: "https://example.com/"},
+		{URL: "https://example.com/", Status: 302, Location: "https://example.com/home"},
+		{URL: "https://example.com/home", Status: 307, Location: "https://example.com/en/home"},
+	}
+	if !reflect.DeepEqual(got.Redirects, wantRedirects) {
+		t.Errorf("redirects = %+v, want %+v", got.Redirects, wantRedirects)
+	}
+	wantHeaders := map[string]string{
+		"Content-Type":   "text/html; charset=utf-8",
+		"Content-Length": "1024",
+		"Set-Cookie":     "a=1\nb=2",
+		"X-Cache-Hit":    "true",
+	}
+	if !reflect.DeepEqual(got.Headers, wantHeaders) {
+		t.Errorf("headers = %v, want %v", got.Headers, wantHeaders)
+	}
+	// Timings count from the first request, not from the last redirect hop,
+	// and milestones not reached stay zero.
+	wantTiming := NavigationTiming{StartedAt: started, ResponseMs: 87.5, DOMContentLoadedMs: 200}
+	if got.Timing != wantTiming {
+		t.Errorf("timing = %+v, want %+v", got.Timing, wantTiming)
+	}
+
+	// A document that failed before its response has no URL, headers or
+	// timings, and the result does not alias the tracker's state.
+	nt.handle(documentRequest("doc2", "L3", "https://example.invalid/", 0))
+	nt.handle(&network.EventLoadingFailed{RequestID: "doc2", ErrorText: "net::ERR_NAME_NOT_RESOLVED"})
+	failed := nt.result("L3")
+	if failed.URL != "" || failed.Status != 0 || len(failed.Headers) != 0 || len(failed.Redirects) != 0 ||
+		failed.Timing.ResponseMs != 0 || failed.Timing.LoadMs != 0 {
+		t.Errorf("failed result = %+v, want an empty document", failed)
+	}
+	got.Redirects[0].Status = 0
+	if nt.result("L1").Redirects[0].Status != 301 {
+		t.Error("result() redirects alias the tracker's")
+	}
+}
+
+func TestNavTrackerReached(t *testing.T) {
+	idleLongAgo := time.Now().Add(-2 * networkIdleWindow)
+	tests := []struct {
+		name        string
+		events      []any
+		idleAt      time.Time
+		until       string
+		want        bool
+		wantRecheck bool
+		wantErr     string
+	}{
+		{name: "nothing yet", until: WaitLoad},
+		{name: "load", events: []any{lifecycle("L1", "load", 10)}, until: WaitLoad, want: true},
+		{name: "commit by lifecycle", events: []any{lifecycle("L1", "commit", 5)}, until: WaitCommit, want: true},
+		{
+			name: "commit by response",
+			events: []any{
+				documentRequest("doc", "L1", "https://example.com/", 0),
+				&network.EventResponseReceived{RequestID: "doc", Response: &network.Response{Status: 200}},
+			},
+			until: WaitCommit,
+			want:  true,
+		},
+		{name: "domcontentloaded pending", events: []any{lifecycle("L1", "commit", 5)}, until: WaitDOMContentLoaded},
+		{name: "domcontentloaded", events: []any{lifecycle("L1", "DOMContentLoaded", 5)}, until: WaitDOMContentLoaded, want: true},
+		{name: "networkidle from chrome", events: []any{lifecycle("L1", "networkIdle", 900)}, until: WaitNetworkIdle, want: true},
+		{name: "networkidle before load", idleAt: idleLongAgo, until: WaitNetworkIdle},
+		{name: "networkidle after quiet window", events: []any{lifecycle("L1", "load", 10)}, idleAt: idleLongAgo, until: WaitNetworkIdle, want: true},
+		{name: "networkidle still in window", events: []any{lifecycle("L1", "load", 10)}, idleAt: time.Now(), until: WaitNetworkIdle, wantRecheck: true},
+		{name: "networkidle while busy", events: []any{lifecycle("L1", "load", 10)}, until: WaitNetworkIdle},
+		{name: "networkalmostidle from chrome", events: []any{lifecycle("L1", "networkAlmostIdle", 900)}, until: WaitNetworkAlmostIdle, want: true},
+		{
+			name: "load failure",
+			events: []any{
+				documentRequest("doc", "L1", "https://example.invalid/", 0),
+				&network.EventLoadingFailed{RequestID: "doc", ErrorText: "net::ERR_NAME_NOT_RESOLVED"},
+			},
+			until:   WaitLoad,
+			wantErr: "net::ERR_NAME_NOT_RESOLVED",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nt := newTestNavTracker()
+			for _, ev := range tt.events {
+				nt.handle(ev)
+			}
+			nt.idleAt, nt.almostIdleAt = tt.idleAt, tt.idleAt
+			got, recheck, err := nt.reached("L1", tt.until)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("reached() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("reached() error = %v", err)
+			}
+			if got != tt.want || (recheck > 0) != tt.wantRecheck {
+				t.Fatalf("reached() = %v, recheck %v; want %v, recheck %v", got, recheck, tt.want, tt.wantRecheck)
+			}
+		})
+	}
+}
+
+func TestNavTrackerCountsInflight(t *testing.T) {
+	nt := newTestNavTracker()
+	request := func(id network.RequestID) {
+		nt.handle(&network.EventRequestWillBeSent{RequestID: id, Request: &network.Request{}})
+	}
+	tests := []struct {
+		event        any
+		idle, almost bool
+	}{
+		{nil, true, true},
+		{"a", false, true},
+		{"b", false, true},
+		{"c", false, false},
+		{&network.EventLoadingFinished{RequestID: "a"}, false, true},
+		{&network.EventLoadingFailed{RequestID: "b"}, false, true},
+		{&network.EventLoadingFinished{RequestID: "c"}, true, true},
+	}
+	nt.countInflight()
+	for i, tt := range tests {
+		switch ev := tt.event.(type) {
+		case string:
+			request(network.RequestID(ev))
+		case nil:
+		default:
+			nt.handle(ev)
+		}
+		if idle := !nt.idleAt.IsZero(); idle != tt.idle {
+			t.Errorf("step %d: idle = %v, want %v", i, idle, tt.idle)
+		}
+		if almost := !nt.almostIdleAt.IsZero(); almost != tt.almost {
+			t.Errorf("step %d: almost idle = %v, want %v", i, almost, tt.almost)
+		}
+	}
+}
+
+func TestNavTrackerNext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	nt := newTestNavTracker()
+	go nt.handle(documentRequest("doc", "L2", "https://example.com/", 0))
+	if loader, err := nt.next(ctx); err != nil || loader != "L2" {
+		t.Fatalf("next() = %q, %v; want L2", loader, err)
+	}
+
+	nt = newTestNavTracker()
+	go nt.handle(lifecycle("L3", "init", 0))
+	if loader, err := nt.next(ctx); err != nil || loader != "L3" {
+		t.Fatalf("next() after a commit without a request = %q, %v; want L3", loader, err)
+	}
+
+	nt = newTestNavTracker()
+	go nt.handle(&page.EventNavigatedWithinDocument{FrameID: "main", URL: "https://example.com/#top"})
+	if loader, err := nt.next(ctx); err != nil || loader != "" {
+		t.Fatalf("next() after same-document navigation = %q, %v; want no loader", loader, err)
+	}
+
+	nt = newTestNavTracker()
+	canceled, cancelNow := context.WithCancel(ctx)
+	cancelNow()
+	if _, err := nt.next(canceled); err != context.Canceled {
+		t.Fatalf("next() on canceled context = %v, want context.Canceled", err)
+	}
+}
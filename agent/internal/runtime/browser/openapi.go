@@ -0,0 +1,205 @@
+package browser
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const openAPIVersion = "3.0.3"
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (r *Runtime) handleOpenAPI(w http.ResponseWriter, req *http.Request) {
+	respondJSON(w, http.StatusOK, r.openAPIDocument())
+}
+
+// openAPIDocument describes every mounted action at its resolved method and
+// path. Request and response schemas are derived from the Go types recorded
+// on each action, so they cannot drift from what the handlers decode.
+func (r *Runtime) openAPIDocument() map[string]any {
+	builtin := make(map[string]bool)
+	for _, action := range r.builtinActions() {
+		builtin[action.Name] = true
+	}
+
+	actions := append([]Action(nil), r.mounted...)
+	sort.Slice(actions, func(i, j int) bool { return actions[i].Name < actions[j].Name })
+
+	schemas := &schemaBuilder{components: make(map[string]any)}
+	errorRef := schemas.schema(reflect.TypeOf(errorResponse{}))
+
+	paths := make(map[string]map[string]any)
+	for _, action := range actions {
+		var params []map[string]any
+		for _, name := range pathParams(action.Path) {
+			params = append(params, map[string]any{
+				"name": name, "in": "path", "required": true,
+				"schema": map[string]any{"type": "string"},
+			})
+		}
+		for _, name := range action.Query {
+			kind := "string"
+			if strings.HasSuffix(name, "_ms") {
+				kind = "integer"
+			}
+			params = append(params, map[string]any{
+				"name": name, "in": "query",
+				"schema": map[string]any{"type": kind},
+			})
+		}
+		if builtin[action.Name] {
+			params = append(params, map[string]any{
+				"name": SessionHeader, "in": "header",
+				"description": "Browser session to act in; the default context when omitted",
+				"schema":      map[string]any{"type": "string"},
+			})
+		}
+
+		success := map[string]any{"type": "object"}
+		if action.Response != nil {
+			success = schemas.schema(reflect.TypeOf(action.Response))
+		}
+		operation := map[string]any{
+			"operationId": action.Name,
+			"summary":     action.Description,
+			"responses": map[string]any{
+				"200": map[string]any{
+					"description": "OK",
+					"content":     map[string]any{"application/json": map[string]any{"schema": success}},
+				},
+				"default": map[string]any{
+					"description": "Error",
+					"content":     map[string]any{"application/json": map[string]any{"schema": errorRef}},
+				},
+			},
+		}
+		if action.TimeoutMs > 0 {
+			operation["x-timeout-ms"] = action.TimeoutMs
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		if action.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": schemas.schema(reflect.TypeOf(action.Request))},
+				},
+			}
+		}
+
+		path := openAPIPath(action.Path)
+		if paths[path] == nil {
+			paths[path] = make(map[string]any)
+		}
+		paths[path][strings.ToLower(action.Method)] = operation
+	}
+
+	version := "dev"
+	if r.manifest != nil && r.manifest.Version != "" {
+		version = r.manifest.Version
+	}
+	return map[string]any{
+		"openapi": openAPIVersion,
+		"info": map[string]any{
+			"title":   "Volant browser plugin",
+			"version": version,
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas.components},
+	}
+}
+
+// openAPIPath strips chi regexp constraints ({id:[0-9]+} -> {id}).
+func openAPIPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			name, _, _ := strings.Cut(strings.Trim(segment, "{}"), ":")
+			segments[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// schemaBuilder converts Go types to OpenAPI schemas, registering named
+// structs as components.
+type schemaBuilder struct {
+	components map[string]any
+}
+
+func (s *schemaBuilder) schema(t reflect.Type) map[string]any {
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := s.schema(t.Elem())
+		if _, isRef := schema["$ref"]; isRef {
+			return map[string]any{"allOf": []any{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]any{"type": "string", "format": "date-time"}
+		}
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		name := componentName(t.Name())
+		if _, ok := s.components[name]; !ok {
+			// Reserve the name first so self-referencing types terminate.
+			s.components[name] = map[string]any{}
+			s.components[name] = s.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	default:
+		return map[string]any{}
+	}
+}
+
+func (s *schemaBuilder) object(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := field.Name
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		properties[name] = s.schema(field.Type)
+	}
+	return map[string]any{"type": "object", "properties": properties}
+}
+
+func componentName(name string) string {
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
@@ -0,0 +1,466 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// resourceTypes are the request types Chrome reports, keyed by their
+// lower-case name.
+var resourceTypes = func() map[string]network.ResourceType {
+	types := make(map[string]network.ResourceType)
+	for _, t := range []network.ResourceType{
+		network.ResourceTypeDocument, network.ResourceTypeStylesheet, network.ResourceTypeImage,
+		network.ResourceTypeMedia, network.ResourceTypeFont, network.ResourceTypeScript,
+		network.ResourceTypeTextTrack, network.ResourceTypeXHR, network.ResourceTypeFetch,
+		network.ResourceTypePrefetch, network.ResourceTypeEventSource, network.ResourceTypeWebSocket,
+		network.ResourceTypeManifest, network.ResourceTypeSignedExchange, network.ResourceTypePing,
+		network.ResourceTypeCSPViolationReport, network.ResourceTypePreflight, network.ResourceTypeOther,
+	} {
+		types[strings.ToLower(t.String())] = t
+	}
+	return types
+}()
+
+// BlockPolicy lists the resource types and URL globs a session refuses to
+// load.
+type BlockPolicy struct {
+	ResourceTypes []string `json:"resource_types"`
+	URLPatterns   []string `json:"url_patterns"`
+}
+
+// BlockStats counts requests refused by a session's block policy.
+type BlockStats struct {
+	Blocked        int64            `json:"blocked"`
+	ByResourceType map[string]int64 `json:"by_resource_type"`
+	ByPattern      map[string]int64 `json:"by_pattern"`
+}
+
+// BlockingInfo is a session's block policy together with its counters.
+type BlockingInfo struct {
+	Policy BlockPolicy `json:"policy"`
+	Stats  BlockStats  `json:"stats"`
+}
+
+// Credentials answer HTTP authentication challenges (basic, digest or
+// proxy) raised while loading a page.
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// HeadersInfo reports the extra headers and the credential user configured
+// on a session. The password is never echoed back.
+type HeadersInfo struct {
+	Headers  map[string]string `json:"headers"`
+	AuthUser string            `json:"auth_user,omitempty"`
+}
+
+// sessionPolicy holds network settings that apply to every tab of a session,
+// including tabs opened after the settings were changed.
+type sessionPolicy struct {
+	mu       sync.Mutex
+	block    *blockRules
+	stats    BlockStats
+	headers  map[string]string
+	auth     *Credentials
+	throttle *Throttling
+
+	// proxyAuth answers challenges from the session's proxy: its own, or the
+	// browser-wide one it inherits.
+	proxyAuth *Credentials
+
+	ignoreCerts   bool
+	certOverrides int64
+	certOrigins   map[string]bool
+
+	// downloads are the files the session's pages downloaded, by ID.
+	downloads map[string]*Download
+}
+
+func newSessionPolicy() *sessionPolicy {
+	return &sessionPolicy{stats: BlockStats{
+		ByResourceType: make(map[string]int64),
+		ByPattern:      make(map[string]int64),
+	}}
+}
+
+// normalize validates the policy and canonicalises resource type names.
+func (p BlockPolicy) normalize() (BlockPolicy, error) {
+	var result BlockPolicy
+	for _, name := range p.ResourceTypes {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		t, ok := resourceTypes[strings.ToLower(name)]
+		if !ok {
+			return BlockPolicy{}, fmt.Errorf("browser: unknown resource type %q", name)
+		}
+		result.ResourceTypes = append(result.ResourceTypes, t.String())
+	}
+	for _, pattern := range p.URLPatterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		if _, err := globPattern(pattern); err != nil {
+			return BlockPolicy{}, fmt.Errorf("browser: invalid url pattern %q: %w", pattern, err)
+		}
+		result.URLPatterns = append(result.URLPatterns, pattern)
+	}
+	sort.Strings(result.ResourceTypes)
+	return result, nil
+}
+
+// blockRules is a normalized BlockPolicy ready for matching.
+type blockRules struct {
+	policy   BlockPolicy
+	types    map[network.ResourceType]bool
+	patterns []*regexp.Regexp
+}
+
+// compileBlock prepares a normalized policy for matching.
+func compileBlock(policy BlockPolicy) *blockRules {
+	rules := &blockRules{
+		policy:   policy,
+		types:    make(map[network.ResourceType]bool, len(policy.ResourceTypes)),
+		patterns: make([]*regexp.Regexp, 0, len(policy.URLPatterns)),
+	}
+	for _, name := range policy.ResourceTypes {
+		rules.types[resourceTypes[strings.ToLower(name)]] = true
+	}
+	for _, pattern := range policy.URLPatterns {
+		compiled, _ := globPattern(pattern)
+		rules.patterns = append(rules.patterns, compiled)
+	}
+	return rules
+}
+
+func (r *blockRules) empty() bool {
+	return r == nil || (len(r.types) == 0 && len(r.patterns) == 0)
+}
+
+// match reports whether the paused request is refused, and the URL pattern
+// that refused it when it was not refused by its resource type.
+func (r *blockRules) match(ev *fetch.EventRequestPaused) (bool, string) {
+	if r == nil {
+		return false, ""
+	}
+	if r.types[ev.ResourceType] {
+		return true, ""
+	}
+	for i, pattern := range r.patterns {
+		if pattern.MatchString(ev.Request.URL) {
+			return true, r.policy.URLPatterns[i]
+		}
+	}
+	return false, ""
+}
+
+func (sp *sessionPolicy) setBlock(policy BlockPolicy) {
+	rules := compileBlock(policy)
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.block = rules
+}
+
+// normalizeHeaders trims header names and rejects empty ones.
+func normalizeHeaders(headers map[string]string) (map[string]string, error) {
+	result := make(map[string]string, len(headers))
+	for name, value := range headers {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, errors.New("browser: header name must not be empty")
+		}
+		result[name] = value
+	}
+	return result, nil
+}
+
+func (sp *sessionPolicy) setHeaders(headers map[string]string) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.headers = headers
+}
+
+// extraHeaders returns the session headers overlaid with overrides, in the
+// form Network.setExtraHTTPHeaders expects.
+func (sp *sessionPolicy) extraHeaders(overrides map[string]string) network.Headers {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	headers := make(network.Headers, len(sp.headers)+len(overrides))
+	for name, value := range sp.headers {
+		headers[name] = value
+	}
+	for name, value := range overrides {
+		headers[name] = value
+	}
+	return headers
+}
+
+func (sp *sessionPolicy) setAuth(creds *Credentials) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.auth = creds
+}
+
+func (sp *sessionPolicy) credentials() *Credentials {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return sp.auth
+}
+
+func (sp *sessionPolicy) setProxyAuth(creds *Credentials) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.proxyAuth = creds
+}
+
+func (sp *sessionPolicy) proxyCredentials() *Credentials {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return sp.proxyAuth
+}
+
+func (sp *sessionPolicy) headersInfo() HeadersInfo {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	info := HeadersInfo{Headers: make(map[string]string, len(sp.headers))}
+	for name, value := range sp.headers {
+		info.Headers[name] = value
+	}
+	if sp.auth != nil {
+		info.AuthUser = sp.auth.Username
+	}
+	return info
+}
+
+func (sp *sessionPolicy) setThrottling(th *Throttling) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	sp.throttle = th
+}
+
+func (sp *sessionPolicy) throttling() *Throttling {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return sp.throttle
+}
+
+// needsFetch reports whether the session's settings require paused requests.
+func (sp *sessionPolicy) needsFetch() bool {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	return !sp.block.empty() || sp.auth != nil || sp.proxyAuth != nil
+}
+
+// blocks reports whether the paused request is refused by the session's block
+// policy or by extra, a per-navigation one, counting it if so.
+func (sp *sessionPolicy) blocks(ev *fetch.EventRequestPaused, extra *blockRules) bool {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	blocked, pattern := sp.block.match(ev)
+	if !blocked {
+		blocked, pattern = extra.match(ev)
+	}
+	if !blocked {
+		return false
+	}
+	sp.stats.Blocked++
+	sp.stats.ByResourceType[ev.ResourceType.String()]++
+	if pattern != "" {
+		sp.stats.ByPattern[pattern]++
+	}
+	return true
+}
+
+func (sp *sessionPolicy) blockingInfo() BlockingInfo {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	var policy BlockPolicy
+	if sp.block != nil {
+		policy = sp.block.policy
+	}
+	info := BlockingInfo{
+		Policy: policy,
+		Stats: BlockStats{
+			Blocked:        sp.stats.Blocked,
+			ByResourceType: make(map[string]int64, len(sp.stats.ByResourceType)),
+			ByPattern:      make(map[string]int64, len(sp.stats.ByPattern)),
+		},
+	}
+	for k, v := range sp.stats.ByResourceType {
+		info.Stats.ByResourceType[k] = v
+	}
+	for k, v := range sp.stats.ByPattern {
+		info.Stats.ByPattern[k] = v
+	}
+	return info
+}
+
+// policy returns the settings for a session, creating them from the startup
+// defaults on first use.
+func (b *Browser) policy(sessionID string) *sessionPolicy {
+	b.policiesMu.Lock()
+	defer b.policiesMu.Unlock()
+	sp, ok := b.policies[sessionID]
+	if !ok {
+		sp = newSessionPolicy()
+		sp.setBlock(b.cfg.BlockPolicy)
+		sp.proxyAuth = b.cfg.Proxy.credentials()
+		sp.ignoreCerts = b.cfg.Certificates.IgnoreErrors
+		b.policies[sessionID] = sp
+	}
+	return sp
+}
+
+// dropPolicy forgets a session's settings.
+func (b *Browser) dropPolicy(sessionID string) {
+	b.policiesMu.Lock()
+	delete(b.policies, sessionID)
+	b.policiesMu.Unlock()
+}
+
+// sessionTabs returns the registered tabs of a session.
+func (b *Browser) sessionTabs(sessionID string) []*tab {
+	b.tabsMu.RLock()
+	defer b.tabsMu.RUnlock()
+	var tabs []*tab
+	for _, t := range b.tabs {
+		if t.session == sessionID {
+			tabs = append(tabs, t)
+		}
+	}
+	return tabs
+}
+
+// Blocking reports the session's block policy and counters.
+func (b *Browser) Blocking(sessionID string) (BlockingInfo, error) {
+	if _, err := b.lookupSession(sessionID); err != nil {
+		return BlockingInfo{}, err
+	}
+	return b.policy(strings.TrimSpace(sessionID)).blockingInfo(), nil
+}
+
+// SetBlocking replaces the session's block policy and applies it to each of
+// its open tabs.
+func (b *Browser) SetBlocking(sessionID string, timeout time.Duration, policy BlockPolicy) (BlockingInfo, error) {
+	if _, err := b.lookupSession(sessionID); err != nil {
+		return BlockingInfo{}, err
+	}
+	sessionID = strings.TrimSpace(sessionID)
+	policy, err := policy.normalize()
+	if err != nil {
+		return BlockingInfo{}, err
+	}
+	sp := b.policy(sessionID)
+	sp.setBlock(policy)
+	if err := b.syncSessionFetch(sessionID, timeout); err != nil {
+		return BlockingInfo{}, err
+	}
+	b.publish("agent", fmt.Sprintf("block policy updated (types=%s, patterns=%d)", strings.Join(policy.ResourceTypes, ","), len(policy.URLPatterns)))
+	return sp.blockingInfo(), nil
+}
+
+// Headers reports the session's extra headers and credential user.
+func (b *Browser) Headers(sessionID string) (HeadersInfo, error) {
+	if _, err := b.lookupSession(sessionID); err != nil {
+		return HeadersInfo{}, err
+	}
+	return b.policy(strings.TrimSpace(sessionID)).headersInfo(), nil
+}
+
+// SetHeaders replaces the headers sent with every request of the session.
+func (b *Browser) SetHeaders(sessionID string, timeout time.Duration, headers map[string]string) (HeadersInfo, error) {
+	if _, err := b.lookupSession(sessionID); err != nil {
+		return HeadersInfo{}, err
+	}
+	sessionID = strings.TrimSpace(sessionID)
+	headers, err := normalizeHeaders(headers)
+	if err != nil {
+		return HeadersInfo{}, err
+	}
+	sp := b.policy(sessionID)
+	sp.setHeaders(headers)
+	for _, t := range b.sessionTabs(sessionID) {
+		err := b.run(string(t.id), timeout, "sync_headers", "", func(ctx context.Context) (string, error) {
+			return "", chromedp.Run(ctx, network.SetExtraHTTPHeaders(sp.extraHeaders(nil)))
+		})
+		if err != nil {
+			return HeadersInfo{}, err
+		}
+	}
+	b.publish("agent", fmt.Sprintf("extra headers updated (%d set)", len(headers)))
+	return sp.headersInfo(), nil
+}
+
+// SetCredentials sets the credentials used to answer the session's HTTP
+// authentication challenges. Nil stops answering them.
+func (b *Browser) SetCredentials(sessionID string, timeout time.Duration, creds *Credentials) (HeadersInfo, error) {
+	if _, err := b.lookupSession(sessionID); err != nil {
+		return HeadersInfo{}, err
+	}
+	sessionID = strings.TrimSpace(sessionID)
+	if creds != nil && creds.Username == "" {
+		return HeadersInfo{}, errors.New("browser: username is required")
+	}
+	sp := b.policy(sessionID)
+	sp.setAuth(creds)
+	if err := b.syncSessionFetch(sessionID, timeout); err != nil {
+		return HeadersInfo{}, err
+	}
+	if creds != nil {
+		b.publish("agent", fmt.Sprintf("http credentials set for %s", creds.Username))
+	} else {
+		b.publish("agent", "http credentials cleared")
+	}
+	return sp.headersInfo(), nil
+}
+
+// applySession pushes the session's network settings onto a tab.
+func (b *Browser) applySession(ctx context.Context, t *tab) error {
+	if err := chromedp.Run(ctx, network.SetExtraHTTPHeaders(b.policy(t.session).extraHeaders(nil))); err != nil {
+		return fmt.Errorf("extra headers: %w", err)
+	}
+	if err := b.syncFetch(ctx, t); err != nil {
+		return fmt.Errorf("interception: %w", err)
+	}
+	if th := b.policy(t.session).throttling(); th != nil {
+		if err := applyThrottling(ctx, th); err != nil {
+			return fmt.Errorf("throttling: %w", err)
+		}
+	}
+	if err := b.applyDownloads(ctx, t); err != nil {
+		return fmt.Errorf("downloads: %w", err)
+	}
+	if b.policy(t.session).ignoresCerts() {
+		if err := applyCertificates(ctx, true); err != nil {
+			return fmt.Errorf("certificates: %w", err)
+		}
+	}
+	return nil
+}
+
+// syncSessionFetch re-evaluates Fetch interception on every tab of a session.
+func (b *Browser) syncSessionFetch(sessionID string, timeout time.Duration) error {
+	for _, t := range b.sessionTabs(sessionID) {
+		err := b.run(string(t.id), timeout, "sync_interception", "", func(ctx context.Context) (string, error) {
+			return "", b.syncFetch(ctx, t)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package browser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chromedp/cdproto/fetch"
+	"github.com/chromedp/cdproto/network"
+)
+
+func pausedRequest(url string, typ network.ResourceType) *fetch.EventRequestPaused {
+	return &fetch.EventRequestPaused{
+		RequestID:    "r1",
+		Request:      &network.Request{URL: url},
+		ResourceType: typ,
+	}
+}
+
+func TestBlockPolicyNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  BlockPolicy
+		want    BlockPolicy
+		wantErr string
+	}{
+		{name: "empty", policy: BlockPolicy{}, want: BlockPolicy{}},
+		{
+			name:   "folds and sorts resource types",
+			policy: BlockPolicy{ResourceTypes: []string{" IMAGE ", "xhr", "", "Font"}},
+			want:   BlockPolicy{ResourceTypes: []string{"Font", "Image", "XHR"}},
+		},
+		{
+			name:   "trims patterns and keeps their order",
+			policy: BlockPolicy{URLPatterns: []string{" *.png", "", "https://ads.example.com/*"}},
+			want:   BlockPolicy{URLPatterns: []string{"*.png", "https://ads.example.com/*"}},
+		},
+		{name: "unknown resource type", policy: BlockPolicy{ResourceTypes: []string{"pictures"}}, wantErr: "unknown resource type"},
+	}
+	for _, tt := range tests {
+		got, err := tt.policy.normalize()
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: normalize() error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: normalize() error = %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: normalize() = %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSessionPolicyBlocks(t *testing.T) {
+	policy, err := BlockPolicy{ResourceTypes: []string{"image"}, URLPatterns: []string{"*.js", "https://ads.example.com/*"}}.normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp := newSessionPolicy()
+	sp.setBlock(policy)
+
+	tests := []struct {
+		url  string
+		typ  network.ResourceType
+		want bool
+	}{
+		{"https://example.com/logo.png", network.ResourceTypeImage, true},
+		{"https://example.com/app.js", network.ResourceTypeScript, true},
+		{"https://example.com/vendor.js", network.ResourceTypeScript, true},
+		{"https://ads.example.com/pixel", network.ResourceTypeXHR, true},
+		{"https://example.com/", network.ResourceTypeDocument, false},
+		{"https://example.com/app.json", network.ResourceTypeFetch, false},
+	}
+	for _, tt := range tests {
+		if got := sp.blocks(pausedRequest(tt.url, tt.typ), nil); got != tt.want {
+			t.Errorf("blocks(%s, %s) = %v, want %v", tt.url, tt.typ, got, tt.want)
+		}
+	}
+
+	info := sp.blockingInfo()
+	if !reflect.DeepEqual(info.Policy, policy) {
+		t.Errorf("policy = %+v, want %+v", info.Policy, policy)
+	}
+	want := BlockStats{
+		Blocked:        4,
+		ByResourceType: map[string]int64{"Image": 1, "Script": 2, "XHR": 1},
+		// A request refused by its type is not counted against a pattern.
+		ByPattern: map[string]int64{"*.js": 2, "https://ads.example.com/*": 1},
+	}
+	if !reflect.DeepEqual(info.Stats, want) {
+		t.Errorf("stats = %+v, want %+v", info.Stats, want)
+	}
+}
+
+func TestSessionPolicyBlocksPerNavigation(t *testing.T) {
+	sp := newSessionPolicy()
+	sp.setBlock(BlockPolicy{URLPatterns: []string{"*.png"}})
+	if sp.blocks(pausedRequest("https://example.com/font.woff2", network.ResourceTypeFont), nil) {
+		t.Fatal("font blocked without a per-navigation policy")
+	}
+
+	extra, err := BlockPolicy{ResourceTypes: []string{"FONT"}, URLPatterns: []string{"*/track?*"}}.normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := compileBlock(extra)
+	for _, ev := range []*fetch.EventRequestPaused{
+		pausedRequest("https://example.com/font.woff2", network.ResourceTypeFont),
+		pausedRequest("https://example.com/track?id=1", network.ResourceTypePing),
+		pausedRequest("https://example.com/logo.png", network.ResourceTypeImage),
+	} {
+		if !sp.blocks(ev, rules) {
+			t.Errorf("blocks(%s) = false with a per-navigation policy", ev.Request.URL)
+		}
+	}
+	if sp.blocks(pausedRequest("https://example.com/", network.ResourceTypeDocument), rules) {
+		t.Error("document blocked by a policy that does not cover it")
+	}
+
+	stats := sp.blockingInfo().Stats
+	want := map[string]int64{"*.png": 1, "*/track?*": 1}
+	if stats.Blocked != 3 || !reflect.DeepEqual(stats.ByPattern, want) {
+		t.Errorf("stats = %+v, want 3 blocked and patterns %v", stats, want)
+	}
+	if policy := sp.blockingInfo().Policy; !reflect.DeepEqual(policy, BlockPolicy{URLPatterns: []string{"*.png"}}) {
+		t.Errorf("session policy = %+v, want the per-navigation policy left out", policy)
+	}
+}
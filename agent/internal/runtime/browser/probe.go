@@ -0,0 +1,147 @@
+package browser
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	cdpbrowser "github.com/chromedp/cdproto/browser"
+	"github.com/chromedp/cdproto/target"
+)
+
+// procRoot is where processTreeRSS reads process information.
+const procRoot = "/proc"
+
+// ProbeResult is the outcome of a readiness round trip over CDP.
+type ProbeResult struct {
+	Product         string `json:"product"`
+	ProtocolVersion string `json:"protocol_version"`
+	PID             int    `json:"pid"`
+	RSSBytes        int64  `json:"rss_bytes"`
+	OpenTargets     int    `json:"open_targets"`
+	Latency         string `json:"latency"`
+}
+
+// Probe performs a cheap CDP round trip (Browser.getVersion followed by
+// Target.getTargets) and reports process metrics for the Chrome tree.
+func (b *Browser) Probe(timeout time.Duration) (ProbeResult, error) {
+	started := time.Now()
+	ctx, cancel, err := b.browserScope(timeout)
+	if err != nil {
+		return ProbeResult{}, err
+	}
+	defer cancel()
+
+	protocolVersion, product, _, _, _, err := cdpbrowser.GetVersion().Do(ctx)
+	if err != nil {
+		return ProbeResult{}, fmt.Errorf("browser: cdp probe: %w", err)
+	}
+	infos, err := target.GetTargets().Do(ctx)
+	if err != nil {
+		return ProbeResult{}, fmt.Errorf("browser: cdp probe: %w", err)
+	}
+
+	result := ProbeResult{
+		Product:         product,
+		ProtocolVersion: protocolVersion,
+		Latency:         time.Since(started).Round(time.Microsecond).String(),
+	}
+	for _, info := range infos {
+		if info.Type == "page" {
+			result.OpenTargets++
+		}
+	}
+
+	b.connMu.RLock()
+	if b.chrome != nil && b.chrome.Process != nil {
+		result.PID = b.chrome.Process.Pid
+	}
+	b.connMu.RUnlock()
+	if result.PID > 0 {
+		result.RSSBytes = processTreeRSS(procRoot, result.PID)
+	}
+	return result, nil
+}
+
+// processTreeRSS sums the resident set size of pid and all of its descendants
+// (Chrome's renderer, GPU and utility processes) from the proc filesystem at
+// root. It returns 0 where /proc is unavailable.
+func processTreeRSS(root string, pid int) int64 {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return 0
+	}
+
+	children := make(map[int][]int)
+	for _, entry := range entries {
+		child, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		if parent, ok := procParent(root, child); ok {
+			children[parent] = append(children[parent], child)
+		}
+	}
+
+	var total int64
+	queue := []int{pid}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		total += procRSS(root, current)
+		queue = append(queue, children[current]...)
+	}
+	return total
+}
+
+func procParent(root string, pid int) (int, bool) {
+	data, err := os.ReadFile(filepath.Join(root, strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0, false
+	}
+	// The command name may contain spaces; fields resume after the last ')'.
+	stat := string(data)
+	idx := strings.LastIndexByte(stat, ')')
+	if idx < 0 {
+		return 0, false
+	}
+	fields := strings.Fields(stat[idx+1:])
+	if len(fields) < 2 {
+		return 0, false
+	}
+	parent, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, false
+	}
+	return parent, true
+}
+
+func procRSS(root string, pid int) int64 {
+	file, err := os.Open(filepath.Join(root, strconv.Itoa(pid), "status"))
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "VmRSS:") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "VmRSS:"))
+		if len(fields) == 0 {
+			return 0
+		}
+		kb, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return 0
+		}
+		return kb * 1024
+	}
+	return 0
+}
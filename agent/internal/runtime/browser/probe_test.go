@@ -0,0 +1,98 @@
+package browser
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fakeProc lays out the stat and status files processTreeRSS reads. A
+// negative rssKB omits the VmRSS line, as for kernel threads.
+type fakeProc struct {
+	pid, ppid int
+	comm      string
+	rssKB     int64
+}
+
+func writeFakeProc(t *testing.T, procs ...fakeProc) string {
+	t.Helper()
+	root := t.TempDir()
+	for _, p := range procs {
+		dir := filepath.Join(root, strconv.Itoa(p.pid))
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		stat := strconv.Itoa(p.pid) + " (" + p.comm + ") S " + strconv.Itoa(p.ppid) + " 1 1 0 -1\n"
+		status := "Name:\t" + p.comm + "\nState:\tS (sleeping)\n"
+		if p.rssKB >= 0 {
+			status += "VmRSS:\t   " + strconv.FormatInt(p.rssKB, 10) + " kB\n"
+		}
+		status += "Threads:\t4\n"
+		for name, data := range map[string]string{"stat": stat, "status": status} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return root
+}
+
+func TestProcessTreeRSS(t *testing.T) {
+	root := writeFakeProc(t,
+		fakeProc{pid: 1, ppid: 0, comm: "init", rssKB: 5000},
+		fakeProc{pid: 100, ppid: 1, comm: "chrome", rssKB: 1000},
+		fakeProc{pid: 101, ppid: 100, comm: "chrome (Renderer) x)", rssKB: 200},
+		fakeProc{pid: 102, ppid: 101, comm: "chrome", rssKB: 30},
+		fakeProc{pid: 103, ppid: 100, comm: "kthread", rssKB: -1},
+		fakeProc{pid: 200, ppid: 1, comm: "sshd", rssKB: 7000},
+	)
+	// Entries that are not processes, or vanish mid-walk, are skipped.
+	if err := os.MkdirAll(filepath.Join(root, "self"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "104"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		pid  int
+		want int64
+	}{
+		{"whole chrome tree", 100, (1000 + 200 + 30) * 1024},
+		{"subtree", 101, (200 + 30) * 1024},
+		{"leaf", 102, 30 * 1024},
+		{"no rss line", 103, 0},
+		{"unknown pid", 999, 0},
+	}
+	for _, tt := range tests {
+		if got := processTreeRSS(root, tt.pid); got != tt.want {
+			t.Errorf("%s: processTreeRSS(%d) = %d, want %d", tt.name, tt.pid, got, tt.want)
+		}
+	}
+	if got := processTreeRSS(filepath.Join(root, "missing"), 100); got != 0 {
+		t.Errorf("processTreeRSS without proc = %d, want 0", got)
+	}
+}
+
+func TestProbeWhileRelaunching(t *testing.T) {
+	b := &Browser{
+		log:        newLogEmitter(),
+		chrome:     &exec.Cmd{Process: &os.Process{Pid: 4242}},
+		restarting: true,
+		restarts:   2,
+		lastCrash:  time.Now().UTC(),
+		crashCause: "signal: killed",
+	}
+	if _, err := b.Probe(time.Second); !errors.Is(err, ErrBrowserUnavailable) {
+		t.Fatalf("Probe() error = %v, want ErrBrowserUnavailable", err)
+	}
+	status := b.Status()
+	if status.State != "restarting" || status.PID != 0 || status.Restarts != 2 || status.LastCrashReason != "signal: killed" {
+		t.Fatalf("Status() = %+v while relaunching", status)
+	}
+}
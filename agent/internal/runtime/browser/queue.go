@@ -0,0 +1,257 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	DefaultMaxConcurrency = 4
+	DefaultQueueSize      = 16
+	drainPollInterval     = 50 * time.Millisecond
+)
+
+var (
+	// ErrQueueFull is returned when a target already has the maximum number
+	// of actions waiting to run.
+	ErrQueueFull = errors.New("browser: target action queue is full")
+
+	errTargetClosed = errors.New("browser: target closed")
+)
+
+// QueueStats reports action queue depth across the browser.
+type QueueStats struct {
+	MaxConcurrency int                `json:"max_concurrency"`
+	QueueSize      int                `json:"queue_size"`
+	Running        int                `json:"running"`
+	Pending        int                `json:"pending"`
+	Targets        []TargetQueueStats `json:"targets"`
+}
+
+// TargetQueueStats reports the queue of a single target.
+type TargetQueueStats struct {
+	TargetID  string `json:"target_id"`
+	SessionID string `json:"session_id,omitempty"`
+	Pending   int    `json:"pending"`
+	Running   bool   `json:"running"`
+}
+
+// Job states. A queued job is started by the worker or abandoned by its
+// caller, whichever comes first.
+const (
+	jobQueued int32 = iota
+	jobStarted
+	jobAbandoned
+)
+
+type queuedAction struct {
+	ctx   context.Context
+	fn    func(ctx context.Context) error
+	err   error
+	done  chan struct{}
+	state atomic.Int32
+}
+
+// actionQueue serialises the actions of one target on a dedicated worker so
+// they run in submission order, while the shared workers semaphore bounds how
+// many targets execute at once.
+type actionQueue struct {
+	jobs    chan *queuedAction
+	workers chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+	stop   chan struct{}
+
+	pending atomic.Int64
+	running atomic.Bool
+}
+
+func newActionQueue(size int, workers chan struct{}) *actionQueue {
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	q := &actionQueue{
+		jobs:    make(chan *queuedAction, size),
+		workers: workers,
+		stop:    make(chan struct{}),
+	}
+	go q.run()
+	return q
+}
+
+// submit enqueues fn and blocks until it has run or been discarded. When ctx
+// is done before fn starts, submit returns right away and fn never runs; once
+// fn has started, submit waits for it, so callers may safely read state
+// captured by fn once submit returns.
+func (q *actionQueue) submit(ctx context.Context, fn func(ctx context.Context) error) error {
+	job := &queuedAction{ctx: ctx, fn: fn, done: make(chan struct{})}
+
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return errTargetClosed
+	}
+	q.pending.Add(1)
+	select {
+	case q.jobs <- job:
+	default:
+		q.pending.Add(-1)
+		q.mu.Unlock()
+		return ErrQueueFull
+	}
+	q.mu.Unlock()
+
+	select {
+	case <-job.done:
+		return job.err
+	case <-ctx.Done():
+		if q.abandon(job) {
+			return ctx.Err()
+		}
+		<-job.done
+		return job.err
+	}
+}
+
+// abandon withdraws a job that has not started yet. It reports false once the
+// worker has started or discarded the job.
+func (q *actionQueue) abandon(job *queuedAction) bool {
+	if !job.state.CompareAndSwap(jobQueued, jobAbandoned) {
+		return false
+	}
+	q.pending.Add(-1)
+	return true
+}
+
+func (q *actionQueue) run() {
+	for {
+		select {
+		case job := <-q.jobs:
+			q.execute(job)
+		case <-q.stop:
+			// No submissions can be accepted after stop is closed, so
+			// draining the buffer releases every remaining waiter.
+			for {
+				select {
+				case job := <-q.jobs:
+					if q.abandon(job) {
+						job.err = errTargetClosed
+					}
+					close(job.done)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (q *actionQueue) execute(job *queuedAction) {
+	defer close(job.done)
+	// The caller gave up while the job sat in the queue.
+	if job.state.Load() == jobAbandoned {
+		return
+	}
+
+	// The job stays pending while it waits for a worker, so depth and Drain
+	// still see it.
+	select {
+	case q.workers <- struct{}{}:
+	case <-job.ctx.Done():
+		if q.abandon(job) {
+			job.err = job.ctx.Err()
+		}
+		return
+	}
+	defer func() { <-q.workers }()
+
+	// Mark the job running before it stops counting as pending so that it
+	// is never invisible to depth.
+	q.running.Store(true)
+	defer q.running.Store(false)
+	if !job.state.CompareAndSwap(jobQueued, jobStarted) {
+		return
+	}
+	q.pending.Add(-1)
+	if err := job.ctx.Err(); err != nil {
+		job.err = err
+		return
+	}
+	// The worker outlives any single request, so a panicking action must not
+	// take the target's queue down with it.
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			job.err = fmt.Errorf("browser: action panicked: %v", recovered)
+		}
+	}()
+	job.err = job.fn(job.ctx)
+}
+
+func (q *actionQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	q.closed = true
+	close(q.stop)
+}
+
+// depth returns the number of queued actions, including the running one.
+func (q *actionQueue) depth() int {
+	depth := int(q.pending.Load())
+	if q.running.Load() {
+		depth++
+	}
+	return depth
+}
+
+// Drain blocks until every target queue is empty or ctx is done. Callers stop
+// accepting new work (for example by shutting down the HTTP server) first.
+func (b *Browser) Drain(ctx context.Context) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	for {
+		if stats := b.QueueStats(); stats.Pending == 0 && stats.Running == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("browser: drain in-flight actions: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// QueueStats summarises pending and running actions per target.
+func (b *Browser) QueueStats() QueueStats {
+	b.tabsMu.RLock()
+	defer b.tabsMu.RUnlock()
+
+	stats := QueueStats{
+		MaxConcurrency: cap(b.workers),
+		QueueSize:      b.cfg.QueueSize,
+		Running:        len(b.workers),
+		Targets:        make([]TargetQueueStats, 0, len(b.tabs)),
+	}
+	for _, t := range b.tabs {
+		pending := int(t.queue.pending.Load())
+		stats.Pending += pending
+		stats.Targets = append(stats.Targets, TargetQueueStats{
+			TargetID:  string(t.id),
+			SessionID: t.session,
+			Pending:   pending,
+			Running:   t.queue.running.Load(),
+		})
+	}
+	sort.Slice(stats.Targets, func(i, j int) bool {
+		return stats.Targets[i].TargetID < stats.Targets[j].TargetID
+	})
+	return stats
+}
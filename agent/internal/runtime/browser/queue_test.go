@@ -0,0 +1,154 @@
+package browser
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/target"
+)
+
+// waitFor polls cond until it holds or a second passes.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestQueueCountsJobWaitingForWorker(t *testing.T) {
+	workers := make(chan struct{}, 1) // MaxConcurrency=1
+	first := newActionQueue(4, workers)
+	second := newActionQueue(4, workers)
+	defer first.close()
+	defer second.close()
+	b := &Browser{
+		cfg:     BrowserConfig{QueueSize: 4},
+		workers: workers,
+		tabs: map[target.ID]*tab{
+			"first":  {id: "first", queue: first},
+			"second": {id: "second", queue: second},
+		},
+	}
+
+	release := make(chan struct{})
+	firstDone := make(chan error, 1)
+	go func() {
+		firstDone <- first.submit(context.Background(), func(context.Context) error {
+			<-release
+			return nil
+		})
+	}()
+	waitFor(t, "first action to run", first.running.Load)
+
+	secondDone := make(chan error, 1)
+	go func() {
+		secondDone <- second.submit(context.Background(), func(context.Context) error { return nil })
+	}()
+	// The second tab's worker has taken the job off its channel and is now
+	// blocked on the shared semaphore.
+	waitFor(t, "second action to be dequeued", func() bool {
+		return second.pending.Load() == 1 && len(second.jobs) == 0
+	})
+
+	if got := second.depth(); got != 1 {
+		t.Fatalf("second.depth() = %d while waiting for a worker, want 1", got)
+	}
+	stats := b.QueueStats()
+	if stats.Pending != 1 || stats.Running != 1 {
+		t.Fatalf("QueueStats() pending=%d running=%d, want 1 and 1", stats.Pending, stats.Running)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 3*drainPollInterval)
+	defer cancel()
+	if err := b.Drain(ctx); err == nil {
+		t.Fatal("Drain returned while an action was still waiting to run")
+	}
+
+	close(release)
+	for _, done := range []chan error{firstDone, secondDone} {
+		if err := <-done; err != nil {
+			t.Fatalf("submit: %v", err)
+		}
+	}
+	if err := b.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain after completion: %v", err)
+	}
+	if first.depth() != 0 || second.depth() != 0 {
+		t.Fatalf("depths after completion = %d, %d, want 0", first.depth(), second.depth())
+	}
+}
+
+func TestQueueCanceledWhileWaitingForWorker(t *testing.T) {
+	workers := make(chan struct{}, 1)
+	workers <- struct{}{} // every slot is taken
+	q := newActionQueue(4, workers)
+	defer q.close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- q.submit(ctx, func(context.Context) error {
+			t.Error("canceled action ran")
+			return nil
+		})
+	}()
+	waitFor(t, "action to be dequeued", func() bool {
+		return q.pending.Load() == 1 && len(q.jobs) == 0
+	})
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("submit = %v, want context.Canceled", err)
+	}
+	if got := q.depth(); got != 0 {
+		t.Fatalf("depth() = %d after cancellation, want 0", got)
+	}
+}
+
+func TestQueueDeadlineCoversWaitBehindLongAction(t *testing.T) {
+	q := newActionQueue(4, make(chan struct{}, 4))
+	defer q.close()
+
+	release := make(chan struct{})
+	longDone := make(chan error, 1)
+	go func() {
+		longDone <- q.submit(context.Background(), func(context.Context) error {
+			<-release // ignores its context, like a stuck CDP call
+			return nil
+		})
+	}()
+	waitFor(t, "long action to run", q.running.Load)
+
+	const deadline = 50 * time.Millisecond
+	ctx, cancel := context.WithTimeout(context.Background(), deadline)
+	defer cancel()
+	started := time.Now()
+	err := q.submit(ctx, func(context.Context) error {
+		t.Error("abandoned action ran")
+		return nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("submit = %v, want context.DeadlineExceeded", err)
+	}
+	if waited := time.Since(started); waited > deadline+500*time.Millisecond {
+		t.Fatalf("submit returned after %s, want about %s", waited, deadline)
+	}
+	if got := q.depth(); got != 1 {
+		t.Fatalf("depth() = %d with only the long action left, want 1", got)
+	}
+
+	close(release)
+	if err := <-longDone; err != nil {
+		t.Fatalf("long action: %v", err)
+	}
+	// The worker skips the abandoned job and the queue keeps serving.
+	if err := q.submit(context.Background(), func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("submit after abandonment: %v", err)
+	}
+	if got := q.depth(); got != 0 {
+		t.Fatalf("depth() = %d after completion, want 0", got)
+	}
+}
@@ -0,0 +1,275 @@
+package browser
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/page"
+	"github.com/chromedp/chromedp"
+)
+
+const defaultResponseMaxBodyBytes = 5 << 20
+
+// Actions that can trigger the response being waited for.
+const (
+	TriggerClick    = "click"
+	TriggerNavigate = "navigate"
+)
+
+// ResponseWait selects the next response to capture. URLPattern is a glob as
+// used by route rules. When Trigger is set it is performed once the listener
+// is in place, so the response it causes cannot be missed.
+type ResponseWait struct {
+	URLPattern   string
+	Method       string
+	ResourceType string
+	Trigger      *ResponseTrigger
+	MaxBodyBytes int64
+
+	pattern *regexp.Regexp
+}
+
+// ResponseTrigger is a click on Selector or a navigation to URL.
+type ResponseTrigger struct {
+	Action   string `json:"action"`
+	Selector string `json:"selector,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+// CapturedResponse is a response together with its decoded body. Bodies that
+// are not valid UTF-8 are base64 encoded. A body known to be larger than
+// MaxBodyBytes is not retrieved, and one found to be larger on retrieval is
+// cut; either way Truncated is set and BodySize reports the full size.
+type CapturedResponse struct {
+	URL          string            `json:"url"`
+	Method       string            `json:"method"`
+	ResourceType string            `json:"resource_type"`
+	Status       int64             `json:"status"`
+	StatusText   string            `json:"status_text"`
+	Headers      map[string]string `json:"headers"`
+	MimeType     string            `json:"mime_type"`
+	Body         string            `json:"body"`
+	BodyBase64   bool              `json:"body_base64"`
+	BodySize     int64             `json:"body_size"`
+	Truncated    bool              `json:"truncated"`
+}
+
+func (rw *ResponseWait) validate() error {
+	if strings.TrimSpace(rw.URLPattern) == "" {
+		rw.URLPattern = "*"
+	}
+	pattern, err := globPattern(rw.URLPattern)
+	if err != nil {
+		return fmt.Errorf("browser: invalid url_pattern %q: %w", rw.URLPattern, err)
+	}
+	rw.pattern = pattern
+	rw.Method = strings.ToUpper(strings.TrimSpace(rw.Method))
+	if rw.MaxBodyBytes <= 0 {
+		rw.MaxBodyBytes = defaultResponseMaxBodyBytes
+	}
+	if rw.Trigger == nil {
+		return nil
+	}
+	switch rw.Trigger.Action = strings.ToLower(strings.TrimSpace(rw.Trigger.Action)); rw.Trigger.Action {
+	case TriggerClick:
+		if rw.Trigger.Selector == "" {
+			return errors.New("browser: click trigger needs a selector")
+		}
+	case TriggerNavigate:
+		if strings.TrimSpace(rw.Trigger.URL) == "" {
+			return errors.New("browser: navigate trigger needs a url")
+		}
+	default:
+		return fmt.Errorf("browser: unknown trigger action %q (want click or navigate)", rw.Trigger.Action)
+	}
+	return nil
+}
+
+// captured is the outcome of a matched request once it has finished loading.
+type captured struct {
+	response CapturedResponse
+	id       network.RequestID
+	err      error
+}
+
+// responseListener follows a tab's network events until a response matching
+// the wait finishes loading. Its state is only touched from the listener
+// goroutine.
+type responseListener struct {
+	rw      *ResponseWait
+	methods map[network.RequestID]string
+	matched map[network.RequestID]*CapturedResponse
+	done    chan captured
+}
+
+func newResponseListener(rw *ResponseWait) *responseListener {
+	return &responseListener{
+		rw:      rw,
+		methods: make(map[network.RequestID]string),
+		matched: make(map[network.RequestID]*CapturedResponse),
+		done:    make(chan captured, 1),
+	}
+}
+
+// matches reports whether a response to a request sent with method is the
+// one being waited for.
+func (rw *ResponseWait) matches(method string, e *network.EventResponseReceived) bool {
+	if rw.Method != "" && rw.Method != method {
+		return false
+	}
+	if rw.ResourceType != "" && !strings.EqualFold(rw.ResourceType, e.Type.String()) {
+		return false
+	}
+	return rw.pattern.MatchString(e.Response.URL)
+}
+
+func (l *responseListener) handle(ev any) {
+	switch e := ev.(type) {
+	case *network.EventRequestWillBeSent:
+		l.methods[e.RequestID] = e.Request.Method
+	case *network.EventResponseReceived:
+		method := l.methods[e.RequestID]
+		if !l.rw.matches(method, e) {
+			return
+		}
+		response := &CapturedResponse{
+			URL:          e.Response.URL,
+			Method:       method,
+			ResourceType: e.Type.String(),
+			Status:       e.Response.Status,
+			StatusText:   e.Response.StatusText,
+			Headers:      make(map[string]string, len(e.Response.Headers)),
+			MimeType:     e.Response.MimeType,
+		}
+		for name, value := range e.Response.Headers {
+			response.Headers[name] = fmt.Sprint(value)
+		}
+		l.matched[e.RequestID] = response
+	case *network.EventDataReceived:
+		// The decoded size decides whether the body is retrieved at all.
+		if response, ok := l.matched[e.RequestID]; ok {
+			response.BodySize += e.DataLength
+		}
+	case *network.EventLoadingFinished:
+		if response, ok := l.matched[e.RequestID]; ok {
+			l.deliver(captured{response: *response, id: e.RequestID})
+		}
+	case *network.EventLoadingFailed:
+		if response, ok := l.matched[e.RequestID]; ok {
+			l.deliver(captured{err: fmt.Errorf("browser: response from %s failed to load: %s", response.URL, e.ErrorText)})
+		}
+	}
+}
+
+// deliver reports the first outcome; later ones are dropped.
+func (l *responseListener) deliver(c captured) {
+	select {
+	case l.done <- c:
+	default:
+	}
+}
+
+// WaitForResponse waits for the next response matching rw, performing its
+// trigger first if one is given, and returns it with its body. Only the
+// trigger waits its turn on the tab's action queue, so the response can also
+// be caused by an action sent separately to the same tab.
+func (b *Browser) WaitForResponse(targetID string, timeout time.Duration, rw ResponseWait) (CapturedResponse, error) {
+	if err := rw.validate(); err != nil {
+		return CapturedResponse{}, err
+	}
+	result, err := b.waitForResponse(targetID, b.timeout(timeout), rw)
+	if err != nil {
+		b.publish("agent", fmt.Sprintf("wait_response failed: %v", err))
+		return CapturedResponse{}, err
+	}
+	return result, nil
+}
+
+func (b *Browser) waitForResponse(targetID string, timeout time.Duration, rw ResponseWait) (CapturedResponse, error) {
+	t, err := b.tab(targetID)
+	if err != nil {
+		return CapturedResponse{}, err
+	}
+	b.publish("agent", fmt.Sprintf("Waiting for response matching %s", rw.URLPattern))
+	started := time.Now()
+	ctx, cancel := context.WithTimeout(t.ctx, timeout)
+	defer cancel()
+
+	listener := newResponseListener(&rw)
+	chromedp.ListenTarget(ctx, listener.handle)
+
+	if rw.Trigger != nil {
+		err := t.queue.submit(ctx, func(ctx context.Context) error {
+			return runTrigger(ctx, rw.Trigger)
+		})
+		if err != nil {
+			return CapturedResponse{}, fmt.Errorf("browser: trigger %s: %w", rw.Trigger.Action, err)
+		}
+	}
+
+	var c captured
+	select {
+	case c = <-listener.done:
+	case <-ctx.Done():
+		return CapturedResponse{}, ctx.Err()
+	}
+	if c.err != nil {
+		return CapturedResponse{}, c.err
+	}
+	result := c.response
+
+	// Bodies over the limit are not pulled out of the browser at all.
+	if result.BodySize > rw.MaxBodyBytes {
+		result.Truncated = true
+	} else {
+		var body []byte
+		err := chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			var err error
+			body, err = network.GetResponseBody(c.id).Do(ctx)
+			return err
+		}))
+		if err != nil {
+			return CapturedResponse{}, fmt.Errorf("browser: response body: %w", err)
+		}
+		result.BodySize = int64(len(body))
+		if result.BodySize > rw.MaxBodyBytes {
+			body = body[:rw.MaxBodyBytes]
+			result.Truncated = true
+		}
+		if utf8.Valid(body) {
+			result.Body = string(body)
+		} else {
+			result.Body = base64.StdEncoding.EncodeToString(body)
+			result.BodyBase64 = true
+		}
+	}
+	b.publish("agent", fmt.Sprintf("captured %s %s (%d) (in %s)", result.Method, result.URL, result.Status, time.Since(started).Round(time.Millisecond)))
+	return result, nil
+}
+
+// runTrigger performs the action expected to cause the awaited response. It
+// does not wait for the page to settle.
+func runTrigger(ctx context.Context, trigger *ResponseTrigger) error {
+	switch trigger.Action {
+	case TriggerClick:
+		return chromedp.Run(ctx,
+			chromedp.WaitVisible(trigger.Selector, chromedp.ByQuery),
+			chromedp.Click(trigger.Selector, chromedp.ByQuery),
+		)
+	default:
+		return chromedp.Run(ctx, chromedp.ActionFunc(func(ctx context.Context) error {
+			_, _, errorText, _, err := page.Navigate(trigger.URL).Do(ctx)
+			if err == nil && errorText != "" {
+				err = fmt.Errorf("page load error %s", errorText)
+			}
+			return err
+		}))
+	}
+}
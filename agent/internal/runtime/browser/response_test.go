@@ -0,0 +1,164 @@
+package browser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+)
+
+func TestResponseWaitValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      ResponseWait
+		want    ResponseWait
+		wantErr string
+	}{
+		{
+			name: "defaults",
+			in:   ResponseWait{},
+			want: ResponseWait{URLPattern: "*", MaxBodyBytes: defaultResponseMaxBodyBytes},
+		},
+		{
+			name: "normalizes method and trigger action",
+			in: ResponseWait{
+				URLPattern:   "*/api/*",
+				Method:       " post ",
+				MaxBodyBytes: 10,
+				Trigger:      &ResponseTrigger{Action: " Click ", Selector: "#save"},
+			},
+			want: ResponseWait{
+				URLPattern:   "*/api/*",
+				Method:       "POST",
+				MaxBodyBytes: 10,
+				Trigger:      &ResponseTrigger{Action: TriggerClick, Selector: "#save"},
+			},
+		},
+		{
+			name: "navigate trigger",
+			in:   ResponseWait{Trigger: &ResponseTrigger{Action: "navigate", URL: "https://example.com/"}},
+			want: ResponseWait{
+				URLPattern:   "*",
+				MaxBodyBytes: defaultResponseMaxBodyBytes,
+				Trigger:      &ResponseTrigger{Action: TriggerNavigate, URL: "https://example.com/"},
+			},
+		},
+		{name: "click without selector", in: ResponseWait{Trigger: &ResponseTrigger{Action: "click"}}, wantErr: "needs a selector"},
+		{name: "navigate without url", in: ResponseWait{Trigger: &ResponseTrigger{Action: "navigate", URL: " "}}, wantErr: "needs a url"},
+		{name: "unknown trigger", in: ResponseWait{Trigger: &ResponseTrigger{Action: "hover"}}, wantErr: "unknown trigger action"},
+	}
+	for _, tt := range tests {
+		rw := tt.in
+		err := rw.validate()
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: validate() error = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: validate() error = %v", tt.name, err)
+			continue
+		}
+		if rw.pattern == nil {
+			t.Errorf("%s: validate() did not compile the url pattern", tt.name)
+		}
+		rw.pattern = nil
+		if !reflect.DeepEqual(rw, tt.want) {
+			t.Errorf("%s: validate() = %+v, want %+v", tt.name, rw, tt.want)
+		}
+	}
+}
+
+func TestResponseWaitMatches(t *testing.T) {
+	response := func(url string, typ network.ResourceType) *network.EventResponseReceived {
+		return &network.EventResponseReceived{Type: typ, Response: &network.Response{URL: url}}
+	}
+	tests := []struct {
+		name   string
+		wait   ResponseWait
+		method string
+		ev     *network.EventResponseReceived
+		want   bool
+	}{
+		{"any response", ResponseWait{}, "GET", response("https://example.com/", network.ResourceTypeDocument), true},
+		{"pattern", ResponseWait{URLPattern: "*/api/items*"}, "GET", response("https://example.com/api/items?page=2", network.ResourceTypeXHR), true},
+		{"pattern miss", ResponseWait{URLPattern: "*/api/items*"}, "GET", response("https://example.com/api/users", network.ResourceTypeXHR), false},
+		{"method", ResponseWait{Method: "post"}, "POST", response("https://example.com/api", network.ResourceTypeFetch), true},
+		{"method miss", ResponseWait{Method: "post"}, "GET", response("https://example.com/api", network.ResourceTypeFetch), false},
+		{"resource type folds case", ResponseWait{ResourceType: "xhr"}, "GET", response("https://example.com/api", network.ResourceTypeXHR), true},
+		{"resource type miss", ResponseWait{ResourceType: "fetch"}, "GET", response("https://example.com/api", network.ResourceTypeXHR), false},
+	}
+	for _, tt := range tests {
+		rw := tt.wait
+		if err := rw.validate(); err != nil {
+			t.Fatalf("%s: validate() error = %v", tt.name, err)
+		}
+		if got := rw.matches(tt.method, tt.ev); got != tt.want {
+			t.Errorf("%s: matches() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestResponseListener(t *testing.T) {
+	rw := ResponseWait{URLPattern: "*/api/*", Method: "POST"}
+	if err := rw.validate(); err != nil {
+		t.Fatal(err)
+	}
+	l := newResponseListener(&rw)
+	for _, ev := range []any{
+		// A GET to the same URL is not the awaited request.
+		&network.EventRequestWillBeSent{RequestID: "get", Request: &network.Request{Method: "GET"}},
+		&network.EventRequestWillBeSent{RequestID: "post", Request: &network.Request{Method: "POST"}},
+		&network.EventResponseReceived{RequestID: "get", Type: network.ResourceTypeFetch, Response: &network.Response{URL: "https://example.com/api/save"}},
+		&network.EventLoadingFinished{RequestID: "get"},
+		&network.EventResponseReceived{RequestID: "post", Type: network.ResourceTypeFetch, Response: &network.Response{
+			URL:        "https://example.com/api/save",
+			Status:     201,
+			StatusText: "Created",
+			MimeType:   "application/json",
+			Headers:    network.Headers{"Content-Length": float64(12)},
+		}},
+		&network.EventDataReceived{RequestID: "post", DataLength: 8},
+		&network.EventDataReceived{RequestID: "post", DataLength: 4},
+		&network.EventDataReceived{RequestID: "get", DataLength: 100},
+		&network.EventLoadingFinished{RequestID: "post"},
+		// Only the first outcome is delivered.
+		&network.EventLoadingFailed{RequestID: "post", ErrorText: "net::ERR_ABORTED"},
+	} {
+		l.handle(ev)
+	}
+
+	select {
+	case c := <-l.done:
+		want := CapturedResponse{
+			URL:          "https://example.com/api/save",
+			Method:       "POST",
+			ResourceType: "Fetch",
+			Status:       201,
+			StatusText:   "Created",
+			Headers:      map[string]string{"Content-Length": "12"},
+			MimeType:     "application/json",
+			BodySize:     12,
+		}
+		if c.err != nil || c.id != "post" || !reflect.DeepEqual(c.response, want) {
+			t.Fatalf("captured %+v, %q, %v; want %+v", c.response, c.id, c.err, want)
+		}
+	default:
+		t.Fatal("no response delivered")
+	}
+	select {
+	case c := <-l.done:
+		t.Fatalf("second outcome delivered: %+v", c)
+	default:
+	}
+
+	l = newResponseListener(&rw)
+	l.handle(&network.EventRequestWillBeSent{RequestID: "post", Request: &network.Request{Method: "POST"}})
+	l.handle(&network.EventResponseReceived{RequestID: "post", Response: &network.Response{URL: "https://example.com/api/save"}})
+	l.handle(&network.EventLoadingFailed{RequestID: "post", ErrorText: "net::ERR_CONNECTION_RESET"})
+	if c := <-l.done; c.err == nil || !strings.Contains(c.err.Error(), "net::ERR_CONNECTION_RESET") {
+		t.Fatalf("failed load delivered %v, want the load error", c.err)
+	}
+}
@@ -2,8 +2,14 @@ package browser
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -15,19 +21,40 @@ const Name = "browser"
 
 // Options configure the browser runtime.
 type Options struct {
-	DefaultTimeout time.Duration
-	RemoteAddr     string
-	RemotePort     int
-	UserDataDir    string
-	ExecPath       string
-	Manifest       *pluginspec.Manifest
+	DefaultTimeout     time.Duration
+	RemoteAddr         string
+	RemotePort         int
+	UserDataDir        string
+	ExecPath           string
+	SessionIdleTimeout time.Duration
+	MaxConcurrency     int
+	QueueSize          int
+	ProfileSnapshot    string
+	BlockPolicy        BlockPolicy
+	Proxy              ProxyConfig
+	Certificates       CertificatePolicy
+	Manifest           *pluginspec.Manifest
+
+	// MaxUploadBytes bounds the request body of an upload; zero means
+	// DefaultMaxUploadBytes.
+	MaxUploadBytes int64
+
+	// RequestTimeout bounds every action that does not stream its
+	// response. Zero leaves requests unbounded.
+	RequestTimeout time.Duration
 }
 
 // Runtime exposes HTTP handlers backed by the Browser automation engine.
 type Runtime struct {
 	real           *Browser
 	defaultTimeout time.Duration
+	requestTimeout time.Duration
+	maxUpload      int64
 	manifest       *pluginspec.Manifest
+	snapshotPath   string
+	extraActions   []Action
+	mounted        []Action
+	dispatch       map[string]Action
 }
 
 // New constructs a new browser runtime.
@@ -42,6 +69,12 @@ func New(ctx context.Context, opts Options) (*Runtime, error) {
 	cfg.UserDataDir = opts.UserDataDir
 	cfg.ExecPath = opts.ExecPath
 	cfg.DefaultTimeout = opts.DefaultTimeout
+	cfg.SessionIdleTimeout = opts.SessionIdleTimeout
+	cfg.MaxConcurrency = opts.MaxConcurrency
+	cfg.QueueSize = opts.QueueSize
+	cfg.BlockPolicy = opts.BlockPolicy
+	cfg.Proxy = opts.Proxy
+	cfg.Certificates = opts.Certificates
 
 	browser, err := NewBrowser(ctx, cfg)
 	if err != nil {
@@ -51,7 +84,10 @@ func New(ctx context.Context, opts Options) (*Runtime, error) {
 	return &Runtime{
 		real:           browser,
 		defaultTimeout: cfg.DefaultTimeout,
+		requestTimeout: opts.RequestTimeout,
+		maxUpload:      opts.MaxUploadBytes,
 		manifest:       opts.Manifest,
+		snapshotPath:   opts.ProfileSnapshot,
 	}, nil
 }
 
@@ -66,35 +102,105 @@ func (r *Runtime) SubscribeLogs(buffer int) (<-chan LogEvent, func()) {
 	return r.real.SubscribeLogs(buffer)
 }
 
+// Status reports the state of the supervised Chrome process.
+func (r *Runtime) Status() SupervisorStatus { return r.real.Status() }
+
+// Probe performs a readiness round trip against Chrome over CDP.
+func (r *Runtime) Probe(timeout time.Duration) (ProbeResult, error) { return r.real.Probe(timeout) }
+
 // BrowserInstance returns the underlying browser controller.
 func (r *Runtime) BrowserInstance() *Browser { return r.real }
 
-// MountRoutes registers HTTP handlers.
-func (r *Runtime) MountRoutes(router chi.Router) {
-	r.mountRoutes(router, r.manifest)
+// MountRoutes registers every action on router at absolute paths, plus
+// POST /v1/actions/{name} for invoking them by name. When a manifest is loaded
+// only the actions it declares are served, at its declared methods, paths and
+// timeouts, and an action the manifest declares but nothing implements is an
+// error.
+func (r *Runtime) MountRoutes(router chi.Router) error {
+	actions, err := r.resolveActions(r.manifest)
+	if err != nil {
+		return err
+	}
+	r.mounted = actions
+	r.dispatch = make(map[string]Action, len(actions))
+	for _, action := range actions {
+		r.dispatch[action.Name] = action
+	}
+	mountActions(router, actions, r.requestTimeout)
+	router.Post("/v1/actions/{name}", r.handleDispatch)
+	return nil
 }
 
 func (r *Runtime) MountRoutesWithManifest(router chi.Router, manifest pluginspec.Manifest) error {
 	r.manifest = &manifest
-	r.mountRoutes(router, r.manifest)
-	return nil
+	return r.MountRoutes(router)
 }
 
-func (r *Runtime) mountRoutes(router chi.Router, manifest *pluginspec.Manifest) {
-	router.Route("/browser", r.mountBrowserRoutes)
-	router.Route("/dom", r.mountDOMRoutes)
-	router.Route("/script", r.mountScriptRoutes)
-	router.Route("/profile", r.mountProfileRoutes)
+// Shutdown waits for in-flight actions to finish, optionally writes a final
+// profile snapshot, and terminates the runtime. The browser is closed even if
+// ctx expires first.
+func (r *Runtime) Shutdown(ctx context.Context) error {
+	defer r.real.Close()
+
+	drainErr := r.real.Drain(ctx)
+	if r.snapshotPath == "" {
+		return drainErr
+	}
+	if err := r.writeProfileSnapshot(ctx); err != nil {
+		return errors.Join(drainErr, err)
+	}
+	return drainErr
 }
 
-// Shutdown terminates the runtime.
-func (r *Runtime) Shutdown(ctx context.Context) error {
-	r.real.Close()
+// writeProfileSnapshot stores the default context's cookies and storage in
+// the same shape /profile/attach accepts, so a later run can restore them.
+func (r *Runtime) writeProfileSnapshot(ctx context.Context) error {
+	timeout := r.defaultTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+		if timeout <= 0 {
+			return fmt.Errorf("browser runtime: profile snapshot: %w", context.DeadlineExceeded)
+		}
+	}
+
+	cookies, err := r.real.GetCookies("", timeout)
+	if err != nil {
+		return fmt.Errorf("browser runtime: profile snapshot: %w", err)
+	}
+	storage, err := r.real.GetStorage("", timeout)
+	if err != nil {
+		return fmt.Errorf("browser runtime: profile snapshot: %w", err)
+	}
+
+	data, err := json.MarshalIndent(profileResponse{
+		Cookies:        mapCookies(cookies),
+		LocalStorage:   storage.Local,
+		SessionStorage: storage.Session,
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("browser runtime: profile snapshot: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.snapshotPath), 0o755); err != nil {
+		return fmt.Errorf("browser runtime: profile snapshot: %w", err)
+	}
+	tmp := r.snapshotPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("browser runtime: profile snapshot: %w", err)
+	}
+	if err := os.Rename(tmp, r.snapshotPath); err != nil {
+		return fmt.Errorf("browser runtime: profile snapshot: %w", err)
+	}
 	return nil
 }
 
-func (r *Runtime) duration(ms int64) time.Duration {
+// duration returns the timeout for a request: the explicit timeout_ms, then
+// the action's declared timeout, then the runtime default.
+func (r *Runtime) duration(req *http.Request, ms int64) time.Duration {
 	if ms <= 0 {
+		if declared, ok := req.Context().Value(actionTimeoutKey{}).(int64); ok && declared > 0 {
+			return time.Duration(declared) * time.Millisecond
+		}
 		if r.defaultTimeout > 0 {
 			return r.defaultTimeout
 		}
@@ -103,6 +209,21 @@ func (r *Runtime) duration(ms int64) time.Duration {
 	return time.Duration(ms) * time.Millisecond
 }
 
+// resolveTarget maps the request's session header and optional target ID to a
+// concrete tab, writing an error response when the lookup fails.
+func (r *Runtime) resolveTarget(w http.ResponseWriter, req *http.Request, targetID string) (string, bool) {
+	resolved, err := r.real.ResolveTarget(sessionID(req), targetID)
+	if err != nil {
+		errorJSON(w, http.StatusNotFound, err)
+		return "", false
+	}
+	return resolved, true
+}
+
+func sessionID(req *http.Request) string {
+	return strings.TrimSpace(req.Header.Get(SessionHeader))
+}
+
 func parseInt(value string) (int, error) {
 	parsed, err := strconv.Atoi(value)
 	if err != nil {
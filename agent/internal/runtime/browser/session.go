@@ -0,0 +1,336 @@
+package browser
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/target"
+)
+
+const (
+	// SessionHeader carries the session ID on API requests. Requests without
+	// it operate on the default browser context.
+	SessionHeader = "X-Browser-Session"
+
+	DefaultSessionIdleTimeout = 15 * time.Minute
+	sessionReapInterval       = 30 * time.Second
+)
+
+// ErrUnknownSession is returned when a request names a session that does not
+// exist or has already expired.
+var ErrUnknownSession = errors.New("browser: unknown session")
+
+// SessionInfo describes an isolated browser context.
+type SessionInfo struct {
+	ID               string    `json:"session_id"`
+	BrowserContextID string    `json:"browser_context_id"`
+	TargetID         string    `json:"target_id,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	LastUsedAt       time.Time `json:"last_used_at"`
+	IdleTimeout      string    `json:"idle_timeout"`
+	Proxy            string    `json:"proxy,omitempty"`
+}
+
+// session is an incognito-style browser context with its own cookie jar and
+// storage, isolated from the default context and from other sessions.
+type session struct {
+	id          string
+	contextID   cdp.BrowserContextID
+	created     time.Time
+	lastUsed    time.Time
+	idleTimeout time.Duration
+	proxy       string
+}
+
+func (s *session) info(active target.ID) SessionInfo {
+	return SessionInfo{
+		ID:               s.id,
+		BrowserContextID: string(s.contextID),
+		TargetID:         string(active),
+		CreatedAt:        s.created,
+		LastUsedAt:       s.lastUsed,
+		IdleTimeout:      s.idleTimeout.String(),
+		Proxy:            s.proxy,
+	}
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 12)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// SessionOptions configure a new session. Zero values fall back to the
+// browser's startup configuration.
+type SessionOptions struct {
+	IdleTimeout time.Duration
+	Block       *BlockPolicy
+	Headers     map[string]string
+	Credentials *Credentials
+	Throttling  *Throttling
+	Proxy       *ProxyConfig
+	// IgnoreCertErrors overrides the browser-wide certificate policy.
+	IgnoreCertErrors *bool
+}
+
+// normalize validates the options and returns them in the form the session
+// policy stores. A proxy without a server is dropped.
+func (o SessionOptions) normalize() (SessionOptions, error) {
+	if o.Block != nil {
+		normalized, err := o.Block.normalize()
+		if err != nil {
+			return SessionOptions{}, err
+		}
+		o.Block = &normalized
+	}
+	headers, err := normalizeHeaders(o.Headers)
+	if err != nil {
+		return SessionOptions{}, err
+	}
+	o.Headers = headers
+	if o.Credentials != nil && o.Credentials.Username == "" {
+		return SessionOptions{}, errors.New("browser: username is required")
+	}
+	if o.Throttling != nil {
+		normalized, err := o.Throttling.normalize()
+		if err != nil {
+			return SessionOptions{}, err
+		}
+		o.Throttling = &normalized
+	}
+	if o.Proxy != nil {
+		normalized, err := o.Proxy.normalize()
+		if err != nil {
+			return SessionOptions{}, err
+		}
+		o.Proxy = nil
+		if normalized.Server != "" {
+			o.Proxy = &normalized
+		}
+	}
+	return o, nil
+}
+
+// CreateSession creates a new browser context and opens its first tab.
+func (b *Browser) CreateSession(timeout time.Duration, opts SessionOptions) (SessionInfo, error) {
+	opts, err := opts.normalize()
+	if err != nil {
+		return SessionInfo{}, err
+	}
+	idleTimeout := opts.IdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = b.cfg.SessionIdleTimeout
+	}
+	proxy := opts.Proxy
+	id, err := newID()
+	if err != nil {
+		return SessionInfo{}, fmt.Errorf("browser: generate session id: %w", err)
+	}
+
+	ctx, cancel, err := b.browserScope(timeout)
+	if err != nil {
+		return SessionInfo{}, err
+	}
+	defer cancel()
+	create := target.CreateBrowserContext().WithDisposeOnDetach(true)
+	if proxy != nil {
+		create = create.WithProxyServer(proxy.Server)
+		if len(proxy.Bypass) > 0 {
+			create = create.WithProxyBypassList(strings.Join(proxy.Bypass, ";"))
+		}
+	}
+	contextID, err := create.Do(ctx)
+	if err != nil {
+		return SessionInfo{}, fmt.Errorf("browser: create browser context: %w", err)
+	}
+
+	now := time.Now().UTC()
+	s := &session{
+		id:          id,
+		contextID:   contextID,
+		created:     now,
+		lastUsed:    now,
+		idleTimeout: idleTimeout,
+	}
+	if proxy != nil {
+		s.proxy = proxy.Server
+	}
+	// Settings must be in place before the first tab registers.
+	sp := b.policy(id)
+	if opts.Block != nil {
+		sp.setBlock(*opts.Block)
+	}
+	sp.setHeaders(opts.Headers)
+	sp.setAuth(opts.Credentials)
+	sp.setThrottling(opts.Throttling)
+	if proxy != nil {
+		sp.setProxyAuth(proxy.credentials())
+	}
+	if opts.IgnoreCertErrors != nil {
+		sp.setIgnoreCerts(*opts.IgnoreCertErrors)
+	}
+	b.sessionsMu.Lock()
+	b.sessions[id] = s
+	b.sessionsMu.Unlock()
+	b.publish("agent", fmt.Sprintf("created session %s (browser context %s)", id, contextID))
+
+	tabInfo, err := b.NewTab(id, timeout, "", true)
+	if err != nil {
+		_ = b.DeleteSession(timeout, id)
+		return SessionInfo{}, err
+	}
+
+	info := s.info(target.ID(tabInfo.ID))
+	return info, nil
+}
+
+// Sessions lists the active sessions.
+func (b *Browser) Sessions() []SessionInfo {
+	b.sessionsMu.RLock()
+	list := make([]*session, 0, len(b.sessions))
+	for _, s := range b.sessions {
+		list = append(list, s)
+	}
+	b.sessionsMu.RUnlock()
+
+	b.tabsMu.RLock()
+	defer b.tabsMu.RUnlock()
+	result := make([]SessionInfo, 0, len(list))
+	for _, s := range list {
+		result = append(result, s.info(b.active[s.id]))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// DeleteSession closes every tab in the session and disposes its browser
+// context, discarding cookies and storage.
+func (b *Browser) DeleteSession(timeout time.Duration, id string) error {
+	b.sessionsMu.Lock()
+	s, ok := b.sessions[id]
+	if ok {
+		delete(b.sessions, id)
+	}
+	b.sessionsMu.Unlock()
+	if !ok {
+		return ErrUnknownSession
+	}
+
+	b.tabsMu.Lock()
+	var owned []*tab
+	for tid, t := range b.tabs {
+		if t.session == id {
+			owned = append(owned, t)
+			delete(b.tabs, tid)
+		}
+	}
+	delete(b.active, id)
+	b.tabsMu.Unlock()
+
+	for _, t := range owned {
+		t.queue.close()
+		if t.cancel != nil {
+			t.cancel()
+		}
+	}
+	b.forgetSessionData(id)
+
+	ctx, cancel, err := b.browserScope(timeout)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	if err := target.DisposeBrowserContext(s.contextID).Do(ctx); err != nil {
+		b.publish("agent", fmt.Sprintf("dispose session %s failed: %v", id, err))
+		return fmt.Errorf("browser: dispose browser context: %w", err)
+	}
+	b.publish("agent", fmt.Sprintf("deleted session %s", id))
+	return nil
+}
+
+// forgetSessionData drops a session's settings and removes its download and
+// upload directories.
+func (b *Browser) forgetSessionData(id string) {
+	b.dropPolicy(id)
+	for _, dir := range []string{b.downloadDir(id), b.uploadDir(id)} {
+		if err := os.RemoveAll(dir); err != nil {
+			b.publish("agent", fmt.Sprintf("session %s: remove %s: %v", id, dir, err))
+		}
+	}
+}
+
+// lookupSession returns the session and refreshes its idle deadline. The
+// empty ID addresses the default browser context and always succeeds.
+func (b *Browser) lookupSession(id string) (*session, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil, nil
+	}
+	b.sessionsMu.Lock()
+	defer b.sessionsMu.Unlock()
+	s, ok := b.sessions[id]
+	if !ok {
+		return nil, ErrUnknownSession
+	}
+	s.lastUsed = time.Now().UTC()
+	return s, nil
+}
+
+// sessionForContext maps a browser context back to the owning session ID.
+func (b *Browser) sessionForContext(contextID cdp.BrowserContextID) string {
+	if contextID == "" {
+		return ""
+	}
+	b.sessionsMu.RLock()
+	defer b.sessionsMu.RUnlock()
+	for _, s := range b.sessions {
+		if s.contextID == contextID {
+			return s.id
+		}
+	}
+	return ""
+}
+
+// reapSessions deletes sessions that have been idle longer than their
+// timeout. It runs until the browser is closed.
+func (b *Browser) reapSessions() {
+	ticker := time.NewTicker(sessionReapInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.lifetime.Done():
+			return
+		case now := <-ticker.C:
+			for _, id := range b.expiredSessions(now) {
+				b.publish("agent", fmt.Sprintf("session %s expired after idle timeout", id))
+				_ = b.DeleteSession(0, id)
+			}
+		}
+	}
+}
+
+// expiredSessions returns the sessions idle for longer than their timeout at
+// now. Sessions without a timeout never expire.
+func (b *Browser) expiredSessions(now time.Time) []string {
+	b.sessionsMu.RLock()
+	defer b.sessionsMu.RUnlock()
+	var expired []string
+	for id, s := range b.sessions {
+		if s.idleTimeout > 0 && now.Sub(s.lastUsed) > s.idleTimeout {
+			expired = append(expired, id)
+		}
+	}
+	sort.Strings(expired)
+	return expired
+}
@@ -0,0 +1,114 @@
+package browser
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/chromedp/cdproto/target"
+)
+
+func newSessionBrowser(sessions ...*session) *Browser {
+	b := &Browser{
+		log:      newLogEmitter(),
+		tabs:     map[target.ID]*tab{},
+		active:   map[string]target.ID{},
+		sessions: map[string]*session{},
+		policies: map[string]*sessionPolicy{},
+	}
+	for _, s := range sessions {
+		b.sessions[s.id] = s
+	}
+	return b
+}
+
+func TestExpiredSessions(t *testing.T) {
+	now := time.Now().UTC()
+	b := newSessionBrowser(
+		&session{id: "idle", lastUsed: now.Add(-2 * time.Minute), idleTimeout: time.Minute},
+		&session{id: "busy", lastUsed: now.Add(-30 * time.Second), idleTimeout: time.Minute},
+		&session{id: "edge", lastUsed: now.Add(-time.Minute), idleTimeout: time.Minute},
+		&session{id: "forever", lastUsed: now.Add(-24 * time.Hour)},
+		&session{id: "stale", lastUsed: now.Add(-time.Hour), idleTimeout: time.Minute},
+	)
+	if got, want := b.expiredSessions(now), []string{"idle", "stale"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expiredSessions() = %v, want %v", got, want)
+	}
+}
+
+func TestLookupSessionRefreshesLastUsed(t *testing.T) {
+	stale := time.Now().UTC().Add(-time.Hour)
+	s := &session{id: "s1", lastUsed: stale, idleTimeout: time.Minute}
+	b := newSessionBrowser(s)
+	if got := b.expiredSessions(time.Now().UTC()); len(got) != 1 {
+		t.Fatalf("expiredSessions() before use = %v", got)
+	}
+
+	got, err := b.lookupSession(" s1 ")
+	if err != nil || got != s {
+		t.Fatalf("lookupSession() = %v, %v", got, err)
+	}
+	if !s.lastUsed.After(stale) {
+		t.Fatalf("lastUsed = %v, want it refreshed past %v", s.lastUsed, stale)
+	}
+	if got := b.expiredSessions(time.Now().UTC()); len(got) != 0 {
+		t.Fatalf("expiredSessions() after use = %v, want none", got)
+	}
+
+	if s, err := b.lookupSession(""); s != nil || err != nil {
+		t.Fatalf("lookupSession(\"\") = %v, %v, want the default context", s, err)
+	}
+	if _, err := b.lookupSession("gone"); !errors.Is(err, ErrUnknownSession) {
+		t.Fatalf("lookupSession(gone) error = %v, want ErrUnknownSession", err)
+	}
+}
+
+func TestUnknownSessionHeader(t *testing.T) {
+	r := &Runtime{real: newSessionBrowser(&session{id: "s1"})}
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+	}{
+		{"resolve target", func(w http.ResponseWriter, req *http.Request) { r.resolveTarget(w, req, "") }},
+		{"session headers", r.handleGetHeaders},
+		{"session throttling", r.handleGetThrottling},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(SessionHeader, "expired")
+		rec := httptest.NewRecorder()
+		tt.handler(rec, req)
+		if rec.Code != http.StatusNotFound || !strings.Contains(rec.Body.String(), ErrUnknownSession.Error()) {
+			t.Errorf("%s: got %d %s, want 404 unknown session", tt.name, rec.Code, rec.Body)
+		}
+	}
+}
+
+func TestCreateSessionRejectsInvalidOptions(t *testing.T) {
+	r := &Runtime{real: newSessionBrowser()}
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"malformed body", `{"block":`, "unexpected EOF"},
+		{"unknown field", `{"blocks":{}}`, "unknown field"},
+		{"resource type", `{"block":{"resource_types":["pictures"]}}`, "unknown resource type"},
+		{"header name", `{"headers":{" ":"x"}}`, "header name must not be empty"},
+		{"credentials", `{"credentials":{"password":"pw"}}`, "username is required"},
+		{"throttling", `{"throttling":{"profile":"carrier-pigeon"}}`, "unknown throttling profile"},
+		{"proxy", `{"proxy":{"bypass":["localhost"]}}`, "proxy server is required"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/v1/sessions", strings.NewReader(tt.body))
+		rec := httptest.NewRecorder()
+		r.handleCreateSession(rec, req)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), tt.want) {
+			t.Errorf("%s: got %d %s, want 400 %q", tt.name, rec.Code, rec.Body, tt.want)
+		}
+	}
+}
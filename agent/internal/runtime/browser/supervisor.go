@@ -0,0 +1,190 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/chromedp/cdproto/cdp"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	restartInitialBackoff = 500 * time.Millisecond
+	restartMaxBackoff     = 30 * time.Second
+)
+
+// ErrBrowserUnavailable is returned while Chrome is down and being relaunched.
+var ErrBrowserUnavailable = errors.New("browser: chrome is restarting")
+
+// SupervisorStatus reports the state of the supervised Chrome process.
+type SupervisorStatus struct {
+	State           string     `json:"state"`
+	PID             int        `json:"pid,omitempty"`
+	StartedAt       time.Time  `json:"started_at"`
+	Restarts        int        `json:"restarts"`
+	LastCrashAt     *time.Time `json:"last_crash_at,omitempty"`
+	LastCrashReason string     `json:"last_crash_reason,omitempty"`
+}
+
+// Status returns the supervisor's view of the Chrome process.
+func (b *Browser) Status() SupervisorStatus {
+	b.connMu.RLock()
+	defer b.connMu.RUnlock()
+
+	status := SupervisorStatus{
+		State:           "running",
+		StartedAt:       b.startedAt,
+		Restarts:        b.restarts,
+		LastCrashReason: b.crashCause,
+	}
+	switch {
+	case b.closing:
+		status.State = "stopped"
+	case b.restarting:
+		status.State = "restarting"
+	}
+	if b.chrome != nil && b.chrome.Process != nil && !b.restarting {
+		status.PID = b.chrome.Process.Pid
+	}
+	if !b.lastCrash.IsZero() {
+		crashed := b.lastCrash
+		status.LastCrashAt = &crashed
+	}
+	return status
+}
+
+// supervise waits for the Chrome process to exit. If it was the live process
+// and the browser is not shutting down, the connection is torn down and
+// Chrome is relaunched with the same configuration.
+func (b *Browser) supervise(cmd *exec.Cmd, exited chan struct{}) {
+	waitErr := cmd.Wait()
+	close(exited)
+
+	b.connMu.Lock()
+	if b.chrome != cmd || b.closing || b.lifetime.Err() != nil {
+		b.connMu.Unlock()
+		return
+	}
+	reason := "exited with status 0"
+	if waitErr != nil {
+		reason = waitErr.Error()
+	}
+	cancel := b.cancel
+	b.restarting = true
+	b.lastCrash = time.Now().UTC()
+	b.crashCause = reason
+	b.connMu.Unlock()
+
+	b.publish("browser", fmt.Sprintf("chrome exited unexpectedly: %s", reason))
+
+	// Everything attached to the old process is gone: its tabs, and the
+	// browser contexts backing sessions.
+	b.resetTargets()
+	if cancel != nil {
+		cancel()
+	}
+	b.relaunch()
+}
+
+// relaunch retries launch with exponential backoff until it succeeds or the
+// browser is closed.
+func (b *Browser) relaunch() {
+	backoff := restartInitialBackoff
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-b.lifetime.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		// A crashed Chrome leaves its profile lock behind, which would make
+		// the new process refuse the same user data dir.
+		for _, name := range []string{"SingletonLock", "SingletonSocket", "SingletonCookie"} {
+			_ = os.Remove(filepath.Join(b.cfg.UserDataDir, name))
+		}
+
+		b.publish("agent", fmt.Sprintf("relaunching chrome (attempt %d)", attempt))
+		err := b.launch()
+		if err == nil {
+			b.connMu.Lock()
+			b.restarts++
+			restarts := b.restarts
+			b.connMu.Unlock()
+			b.publish("agent", fmt.Sprintf("chrome restarted (restart #%d)", restarts))
+			return
+		}
+
+		b.connMu.Lock()
+		b.crashCause = fmt.Sprintf("relaunch failed: %v", err)
+		b.connMu.Unlock()
+		b.publish("agent", fmt.Sprintf("chrome relaunch failed: %v", err))
+
+		backoff *= 2
+		if backoff > restartMaxBackoff {
+			backoff = restartMaxBackoff
+		}
+	}
+}
+
+// resetTargets drops every tab and session, failing their queued actions.
+func (b *Browser) resetTargets() {
+	b.tabsMu.Lock()
+	for id, t := range b.tabs {
+		t.queue.close()
+		delete(b.tabs, id)
+	}
+	b.active = make(map[string]target.ID)
+	b.tabsMu.Unlock()
+
+	dropped := make(map[string]bool)
+	b.sessionsMu.Lock()
+	for id := range b.sessions {
+		dropped[id] = true
+	}
+	b.sessions = make(map[string]*session)
+	b.sessionsMu.Unlock()
+
+	// The default context's settings and downloads carry over to the
+	// relaunched browser; every other session is gone along with its browser
+	// context. Staged uploads were only needed by the tabs just dropped.
+	b.policiesMu.Lock()
+	for id := range b.policies {
+		if id != "" {
+			dropped[id] = true
+		}
+	}
+	b.policiesMu.Unlock()
+	for id := range dropped {
+		b.forgetSessionData(id)
+	}
+	if err := os.RemoveAll(b.uploadDir("")); err != nil {
+		b.publish("agent", fmt.Sprintf("remove %s: %v", b.uploadDir(""), err))
+	}
+}
+
+// root returns the chromedp context owning the current browser connection.
+func (b *Browser) root() (context.Context, error) {
+	b.connMu.RLock()
+	defer b.connMu.RUnlock()
+	if b.restarting || b.ctx == nil {
+		return nil, ErrBrowserUnavailable
+	}
+	return b.ctx, nil
+}
+
+// browserScope returns a context bound to the browser-level CDP executor, for
+// Target and Browser domain commands that are not scoped to a page.
+func (b *Browser) browserScope(timeout time.Duration) (context.Context, context.CancelFunc, error) {
+	root, err := b.root()
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithTimeout(root, b.timeout(timeout))
+	return cdp.WithExecutor(ctx, chromedp.FromContext(root).Browser), cancel, nil
+}
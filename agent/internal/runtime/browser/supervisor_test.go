@@ -0,0 +1,49 @@
+package browser
+
+import (
+	"os"
+	"testing"
+
+	"github.com/chromedp/cdproto/target"
+)
+
+func TestResetTargetsRemovesSessionDirectories(t *testing.T) {
+	b := &Browser{
+		cfg:      BrowserConfig{UserDataDir: t.TempDir()},
+		log:      newLogEmitter(),
+		tabs:     make(map[target.ID]*tab),
+		active:   make(map[string]target.ID),
+		sessions: map[string]*session{"s1": {id: "s1"}},
+		policies: make(map[string]*sessionPolicy),
+	}
+	defer b.log.Close()
+	b.policy("")
+	b.policy("s1")
+
+	dirs := map[string]bool{
+		b.downloadDir("s1"): false,
+		b.uploadDir("s1"):   false,
+		b.downloadDir(""):   true,
+		b.uploadDir(""):     false,
+	}
+	for dir := range dirs {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b.resetTargets()
+
+	for dir, kept := range dirs {
+		_, err := os.Stat(dir)
+		if exists := err == nil; exists != kept {
+			t.Errorf("%s exists = %v after reset, want %v", dir, exists, kept)
+		}
+	}
+	if _, ok := b.policies["s1"]; ok {
+		t.Error("session policy survived reset")
+	}
+	if _, ok := b.policies[""]; !ok {
+		t.Error("default context policy was dropped")
+	}
+}
@@ -0,0 +1,404 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/target"
+	"github.com/chromedp/chromedp"
+)
+
+// ErrNoTarget is returned when an action has no explicit target and no tab is
+// currently active.
+var ErrNoTarget = errors.New("browser: no active target")
+
+// TargetInfo describes a page target (tab) managed by the browser.
+type TargetInfo struct {
+	ID         string    `json:"target_id"`
+	SessionID  string    `json:"session_id,omitempty"`
+	URL        string    `json:"url"`
+	Title      string    `json:"title"`
+	Active     bool      `json:"active"`
+	Attached   bool      `json:"attached"`
+	QueueDepth int       `json:"queue_depth"`
+	CreatedAt  time.Time `json:"created_at,omitempty"`
+}
+
+// tab tracks the chromedp context bound to a single page target. Tabs with an
+// empty session belong to the default browser context.
+type tab struct {
+	id        target.ID
+	session   string
+	ctx       context.Context
+	cancel    context.CancelFunc
+	created   time.Time
+	queue     *actionQueue
+	intercept *interceptor
+	har       *harRecorder
+	ws        *wsRecorder
+}
+
+// registerTab records a tab, makes it active when no other tab in its session
+// is, and applies the session's network settings to it.
+func (b *Browser) registerTab(t *tab) {
+	t.queue = newActionQueue(b.cfg.QueueSize, b.workers)
+	t.intercept = newInterceptor()
+
+	b.tabsMu.Lock()
+	b.tabs[t.id] = t
+	if b.active[t.session] == "" {
+		b.active[t.session] = t.id
+	}
+	b.tabsMu.Unlock()
+
+	b.watchCertificates(t)
+	b.watchDownloads(t)
+	// Apply the session's network settings before the tab loads anything.
+	if err := b.applySession(t.ctx, t); err != nil {
+		b.publish("agent", fmt.Sprintf("target %s: apply session settings: %v", t.id, err))
+	}
+}
+
+// forgetTab drops a tab from the registry, promoting the most recently
+// created remaining tab of the same session when the active one disappears.
+func (b *Browser) forgetTab(id target.ID) *tab {
+	b.tabsMu.Lock()
+	defer b.tabsMu.Unlock()
+	t, ok := b.tabs[id]
+	if !ok {
+		return nil
+	}
+	delete(b.tabs, id)
+	t.queue.close()
+	if b.active[t.session] == id {
+		delete(b.active, t.session)
+		var newest *tab
+		for _, candidate := range b.tabs {
+			if candidate.session != t.session {
+				continue
+			}
+			if newest == nil || candidate.created.After(newest.created) {
+				newest = candidate
+			}
+		}
+		if newest != nil {
+			b.active[t.session] = newest.id
+		}
+	}
+	return t
+}
+
+// watchTargets prunes tabs that are closed from within the page (for example
+// via window.close) so stale target IDs are not handed out.
+func (b *Browser) watchTargets(root context.Context) {
+	chromedp.ListenBrowser(root, func(ev any) {
+		destroyed, ok := ev.(*target.EventTargetDestroyed)
+		if !ok {
+			return
+		}
+		if t := b.forgetTab(destroyed.TargetID); t != nil {
+			b.publish("agent", fmt.Sprintf("target %s closed", t.id))
+			b.dropUploads(t)
+			if t.cancel != nil {
+				go t.cancel()
+			}
+		}
+	})
+}
+
+// ResolveTarget maps an optional target ID to a concrete tab within the given
+// session. An empty target ID selects the session's active tab; an explicit
+// one must belong to the session.
+func (b *Browser) ResolveTarget(sessionID, targetID string) (string, error) {
+	if _, err := b.lookupSession(sessionID); err != nil {
+		return "", err
+	}
+	sessionID = strings.TrimSpace(sessionID)
+	targetID = strings.TrimSpace(targetID)
+	if targetID == "" {
+		b.tabsMu.RLock()
+		active := b.active[sessionID]
+		b.tabsMu.RUnlock()
+		if active == "" {
+			return "", ErrNoTarget
+		}
+		return string(active), nil
+	}
+
+	b.tabsMu.RLock()
+	t, ok := b.tabs[target.ID(targetID)]
+	b.tabsMu.RUnlock()
+	if ok {
+		if t.session != sessionID {
+			return "", foreignTarget(targetID)
+		}
+		return string(t.id), nil
+	}
+
+	// Check the owner of an unknown target before attaching to it, so a tab of
+	// another session is never registered or given this session's settings.
+	info, err := b.pageTarget(target.ID(targetID))
+	if err != nil {
+		return "", err
+	}
+	if b.sessionForContext(info.BrowserContextID) != sessionID {
+		return "", foreignTarget(targetID)
+	}
+	t, err = b.attachTarget(info)
+	if err != nil {
+		return "", err
+	}
+	return string(t.id), nil
+}
+
+func foreignTarget(id string) error {
+	return fmt.Errorf("browser: target %q does not belong to this session", id)
+}
+
+// tab resolves the requested target, falling back to the default context's
+// active tab when id is empty. Page targets opened outside the agent (popups,
+// window.open) are attached lazily on first use.
+func (b *Browser) tab(id string) (*tab, error) {
+	if _, err := b.root(); err != nil {
+		return nil, err
+	}
+	id = strings.TrimSpace(id)
+
+	b.tabsMu.RLock()
+	if id == "" {
+		id = string(b.active[""])
+	}
+	t, ok := b.tabs[target.ID(id)]
+	b.tabsMu.RUnlock()
+
+	if id == "" {
+		return nil, ErrNoTarget
+	}
+	if ok {
+		return t, nil
+	}
+	return b.attachTab(target.ID(id))
+}
+
+func (b *Browser) attachTab(id target.ID) (*tab, error) {
+	info, err := b.pageTarget(id)
+	if err != nil {
+		return nil, err
+	}
+	return b.attachTarget(info)
+}
+
+// pageTarget looks up a page target the agent has not registered yet.
+func (b *Browser) pageTarget(id target.ID) (*target.Info, error) {
+	root, err := b.root()
+	if err != nil {
+		return nil, err
+	}
+	infos, err := chromedp.Targets(root)
+	if err != nil {
+		return nil, fmt.Errorf("browser: list targets: %w", err)
+	}
+	for _, info := range infos {
+		if info.TargetID == id && info.Type == "page" {
+			return info, nil
+		}
+	}
+	return nil, fmt.Errorf("browser: unknown target %q", id)
+}
+
+func (b *Browser) attachTarget(found *target.Info) (*tab, error) {
+	root, err := b.root()
+	if err != nil {
+		return nil, err
+	}
+	id := found.TargetID
+	ctx, cancel := chromedp.NewContext(root, chromedp.WithTargetID(id))
+	if err := chromedp.Run(ctx, network.Enable()); err != nil {
+		cancel()
+		return nil, fmt.Errorf("browser: attach target %s: %w", id, err)
+	}
+	t := &tab{
+		id:      id,
+		session: b.sessionForContext(found.BrowserContextID),
+		ctx:     ctx,
+		cancel:  cancel,
+		created: time.Now().UTC(),
+	}
+	b.registerTab(t)
+	b.publish("agent", fmt.Sprintf("attached to target %s", id))
+	return t, nil
+}
+
+// NewTab opens a new page target in the session, optionally navigating it and
+// making it the session's active tab.
+func (b *Browser) NewTab(sessionID string, timeout time.Duration, url string, activate bool) (TargetInfo, error) {
+	s, err := b.lookupSession(sessionID)
+	if err != nil {
+		return TargetInfo{}, err
+	}
+	root, err := b.root()
+	if err != nil {
+		return TargetInfo{}, err
+	}
+	var opts []chromedp.ContextOption
+	if s != nil {
+		opts = append(opts, chromedp.WithExistingBrowserContext(s.contextID))
+	}
+
+	ctx, cancel := chromedp.NewContext(root, opts...)
+	// The first Run attaches the target; it must not carry the action timeout
+	// or the target would be torn down once the deadline passes.
+	if err := chromedp.Run(ctx, network.Enable()); err != nil {
+		cancel()
+		return TargetInfo{}, fmt.Errorf("browser: open tab: %w", err)
+	}
+	t := &tab{
+		id:      chromedp.FromContext(ctx).Target.TargetID,
+		ctx:     ctx,
+		cancel:  cancel,
+		created: time.Now().UTC(),
+	}
+	if s != nil {
+		t.session = s.id
+	}
+	b.registerTab(t)
+	b.publish("agent", fmt.Sprintf("opened target %s", t.id))
+
+	if activate {
+		if err := b.ActivateTab(sessionID, timeout, string(t.id)); err != nil {
+			return TargetInfo{}, err
+		}
+	}
+	if strings.TrimSpace(url) != "" {
+		if _, err := b.Navigate(string(t.id), timeout, url, NavigateOptions{}); err != nil {
+			return TargetInfo{}, err
+		}
+	}
+	return b.TabInfo(sessionID, timeout, string(t.id))
+}
+
+// Tabs lists the open page targets of a session.
+func (b *Browser) Tabs(sessionID string, timeout time.Duration) ([]TargetInfo, error) {
+	if _, err := b.lookupSession(sessionID); err != nil {
+		return nil, err
+	}
+	sessionID = strings.TrimSpace(sessionID)
+
+	ctx, cancel, err := b.browserScope(timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer cancel()
+
+	infos, err := target.GetTargets().Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("browser: list targets: %w", err)
+	}
+
+	result := make([]TargetInfo, 0, len(infos))
+	for _, info := range infos {
+		if info.Type != "page" || b.sessionForContext(info.BrowserContextID) != sessionID {
+			continue
+		}
+		result = append(result, TargetInfo{
+			ID:        string(info.TargetID),
+			SessionID: sessionID,
+			URL:       info.URL,
+			Title:     info.Title,
+		})
+	}
+
+	b.tabsMu.RLock()
+	for i := range result {
+		id := target.ID(result[i].ID)
+		result[i].Active = b.active[sessionID] == id
+		if t, ok := b.tabs[id]; ok {
+			result[i].Attached = true
+			result[i].QueueDepth = t.queue.depth()
+			result[i].CreatedAt = t.created
+		}
+	}
+	b.tabsMu.RUnlock()
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result, nil
+}
+
+// TabInfo returns metadata for a single target.
+func (b *Browser) TabInfo(sessionID string, timeout time.Duration, id string) (TargetInfo, error) {
+	tabs, err := b.Tabs(sessionID, timeout)
+	if err != nil {
+		return TargetInfo{}, err
+	}
+	for _, info := range tabs {
+		if info.ID == id {
+			return info, nil
+		}
+	}
+	return TargetInfo{}, fmt.Errorf("browser: unknown target %q", id)
+}
+
+// ActivateTab brings the target to the foreground and makes it the default for
+// actions in its session that do not name a target.
+func (b *Browser) ActivateTab(sessionID string, timeout time.Duration, id string) error {
+	resolved, err := b.ResolveTarget(sessionID, id)
+	if err != nil {
+		return err
+	}
+	t, err := b.tab(resolved)
+	if err != nil {
+		return err
+	}
+	ctx, cancel, err := b.browserScope(timeout)
+	if err != nil {
+		return err
+	}
+	defer cancel()
+	if err := target.ActivateTarget(t.id).Do(ctx); err != nil {
+		return fmt.Errorf("browser: activate target %s: %w", t.id, err)
+	}
+
+	b.tabsMu.Lock()
+	b.active[t.session] = t.id
+	b.tabsMu.Unlock()
+	b.publish("agent", fmt.Sprintf("activated target %s", t.id))
+	return nil
+}
+
+// CloseTab closes the target and releases its chromedp context.
+func (b *Browser) CloseTab(sessionID string, timeout time.Duration, id string) error {
+	resolved, err := b.ResolveTarget(sessionID, id)
+	if err != nil {
+		return err
+	}
+	t, err := b.tab(resolved)
+	if err != nil {
+		return err
+	}
+	b.forgetTab(t.id)
+	b.dropUploads(t)
+
+	if t.cancel != nil {
+		t.cancel()
+	} else {
+		// The initial tab shares its context with the browser connection, so
+		// close the target directly instead of cancelling the context.
+		ctx, cancel, scopeErr := b.browserScope(timeout)
+		if scopeErr != nil {
+			return scopeErr
+		}
+		defer cancel()
+		if err := target.CloseTarget(t.id).Do(ctx); err != nil {
+			return fmt.Errorf("browser: close target %s: %w", t.id, err)
+		}
+	}
+	b.publish("agent", fmt.Sprintf("closed target %s", t.id))
+	return nil
+}
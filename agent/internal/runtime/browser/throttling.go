@@ -0,0 +1,128 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chromedp/cdproto/emulation"
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+// Throttling slows down the network and CPU of every tab in a session.
+// Zero throughput means unlimited. A profile fills in the network fields;
+// CPURate is kept as given since profiles only describe the link.
+type Throttling struct {
+	Profile      string  `json:"profile,omitempty"`
+	Offline      bool    `json:"offline"`
+	LatencyMs    float64 `json:"latency_ms"`
+	DownloadKbps float64 `json:"download_kbps"`
+	UploadKbps   float64 `json:"upload_kbps"`
+	CPURate      float64 `json:"cpu_rate,omitempty"`
+}
+
+// throttlingProfiles mirror the DevTools network presets.
+var throttlingProfiles = map[string]Throttling{
+	"slow-3g": {Profile: "slow-3g", LatencyMs: 2000, DownloadKbps: 400, UploadKbps: 400},
+	"fast-3g": {Profile: "fast-3g", LatencyMs: 562.5, DownloadKbps: 1440, UploadKbps: 675},
+	"offline": {Profile: "offline", Offline: true},
+}
+
+func (th Throttling) normalize() (Throttling, error) {
+	if name := strings.TrimSpace(th.Profile); name != "" {
+		key := strings.ToLower(strings.NewReplacer(" ", "-", "_", "-").Replace(name))
+		preset, ok := throttlingProfiles[key]
+		if !ok {
+			names := make([]string, 0, len(throttlingProfiles))
+			for name := range throttlingProfiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return Throttling{}, fmt.Errorf("browser: unknown throttling profile %q (want %s)", name, strings.Join(names, ", "))
+		}
+		preset.CPURate = th.CPURate
+		th = preset
+	}
+	if th.LatencyMs < 0 || th.DownloadKbps < 0 || th.UploadKbps < 0 {
+		return Throttling{}, errors.New("browser: latency and throughput must not be negative")
+	}
+	if th.CPURate != 0 && th.CPURate < 1 {
+		return Throttling{}, errors.New("browser: cpu_rate must be at least 1")
+	}
+	return th, nil
+}
+
+// throughput converts kbit/s to the bytes per second the protocol expects,
+// where -1 disables the limit.
+func throughput(kbps float64) float64 {
+	if kbps <= 0 {
+		return -1
+	}
+	return kbps * 1000 / 8
+}
+
+// applyThrottling emulates th on the tab, or lifts all throttling when th is
+// nil.
+func applyThrottling(ctx context.Context, th *Throttling) error {
+	conditions := network.EmulateNetworkConditions(false, 0, -1, -1)
+	rate := 1.0
+	if th != nil {
+		conditions = network.EmulateNetworkConditions(th.Offline, th.LatencyMs, throughput(th.DownloadKbps), throughput(th.UploadKbps))
+		if th.Offline {
+			conditions = conditions.WithConnectionType(network.ConnectionTypeNone)
+		} else if th.Profile != "" {
+			conditions = conditions.WithConnectionType(network.ConnectionTypeCellular3g)
+		}
+		if th.CPURate > 1 {
+			rate = th.CPURate
+		}
+	}
+	return chromedp.Run(ctx, conditions, emulation.SetCPUThrottlingRate(rate))
+}
+
+// Throttling reports the session's throttling; nil when none is applied.
+func (b *Browser) Throttling(sessionID string) (*Throttling, error) {
+	if _, err := b.lookupSession(sessionID); err != nil {
+		return nil, err
+	}
+	return b.policy(strings.TrimSpace(sessionID)).throttling(), nil
+}
+
+// SetThrottling applies th to every tab of the session, including tabs opened
+// later. Nil lifts throttling.
+func (b *Browser) SetThrottling(sessionID string, timeout time.Duration, th *Throttling) (*Throttling, error) {
+	if _, err := b.lookupSession(sessionID); err != nil {
+		return nil, err
+	}
+	sessionID = strings.TrimSpace(sessionID)
+	if th != nil {
+		normalized, err := th.normalize()
+		if err != nil {
+			return nil, err
+		}
+		th = &normalized
+	}
+	sp := b.policy(sessionID)
+	sp.setThrottling(th)
+	for _, t := range b.sessionTabs(sessionID) {
+		err := b.run(string(t.id), timeout, "sync_throttling", "", func(ctx context.Context) (string, error) {
+			return "", applyThrottling(ctx, th)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case th == nil:
+		b.publish("agent", "throttling lifted")
+	case th.Profile != "":
+		b.publish("agent", fmt.Sprintf("throttling set to %s (cpu x%g)", th.Profile, max(th.CPURate, 1)))
+	default:
+		b.publish("agent", fmt.Sprintf("throttling set (latency=%gms, down=%gkbps, up=%gkbps, cpu x%g)", th.LatencyMs, th.DownloadKbps, th.UploadKbps, max(th.CPURate, 1)))
+	}
+	return th, nil
+}
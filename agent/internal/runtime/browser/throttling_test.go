@@ -0,0 +1,109 @@
+package browser
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestThrottlingNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      Throttling
+		want    Throttling
+		wantErr string
+	}{
+		{
+			name: "custom values pass through",
+			in:   Throttling{LatencyMs: 150, DownloadKbps: 1600, UploadKbps: 750, CPURate: 4},
+			want: Throttling{LatencyMs: 150, DownloadKbps: 1600, UploadKbps: 750, CPURate: 4},
+		},
+		{
+			name: "zero means unthrottled",
+			in:   Throttling{},
+			want: Throttling{},
+		},
+		{
+			name: "profile replaces network fields",
+			in:   Throttling{Profile: "slow-3g", LatencyMs: 1, DownloadKbps: 99999},
+			want: throttlingProfiles["slow-3g"],
+		},
+		{
+			name: "profile keeps cpu rate",
+			in:   Throttling{Profile: "fast-3g", CPURate: 6},
+			want: Throttling{Profile: "fast-3g", LatencyMs: 562.5, DownloadKbps: 1440, UploadKbps: 675, CPURate: 6},
+		},
+		{
+			name: "profile name is forgiving",
+			in:   Throttling{Profile: " Slow_3G "},
+			want: throttlingProfiles["slow-3g"],
+		},
+		{
+			name: "profile with spaces",
+			in:   Throttling{Profile: "Fast 3G"},
+			want: throttlingProfiles["fast-3g"],
+		},
+		{
+			name: "offline profile",
+			in:   Throttling{Profile: "offline"},
+			want: Throttling{Profile: "offline", Offline: true},
+		},
+		{
+			name:    "unknown profile lists the known ones",
+			in:      Throttling{Profile: "4g"},
+			wantErr: `unknown throttling profile "4g" (want fast-3g, offline, slow-3g)`,
+		},
+		{
+			name:    "negative latency",
+			in:      Throttling{LatencyMs: -1},
+			wantErr: "must not be negative",
+		},
+		{
+			name:    "negative throughput",
+			in:      Throttling{UploadKbps: -5},
+			wantErr: "must not be negative",
+		},
+		{
+			name:    "cpu rate below one",
+			in:      Throttling{CPURate: 0.5},
+			wantErr: "cpu_rate must be at least 1",
+		},
+		{
+			name:    "cpu rate checked with a profile",
+			in:      Throttling{Profile: "slow-3g", CPURate: 0.5},
+			wantErr: "cpu_rate must be at least 1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.in.normalize()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("normalize() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("normalize() error = %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("normalize() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestThroughput(t *testing.T) {
+	tests := []struct {
+		kbps, want float64
+	}{
+		{0, -1},
+		{-10, -1},
+		{8, 1000},
+		{1440, 180000},
+	}
+	for _, tt := range tests {
+		if got := throughput(tt.kbps); got != tt.want {
+			t.Errorf("throughput(%v) = %v, want %v", tt.kbps, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	// DefaultMaxUploadBytes bounds the request body of an upload.
+	DefaultMaxUploadBytes = 256 << 20
+
+	uploadsDirName = "uploads"
+
+	// uploadMemoryBytes is how much of a multipart upload is held in memory
+	// before parts spill to temporary files.
+	uploadMemoryBytes = 32 << 20
+
+	// uploadField is the multipart field that carries the files.
+	uploadField = "files"
+)
+
+// UploadFile is a file to put into a file input. Name becomes the file name
+// the page sees. Open is called once, when the file is staged, and the
+// content is closed right after.
+type UploadFile struct {
+	Name string
+	Open func() (io.ReadCloser, error)
+}
+
+// UploadedFile describes a file set on the input.
+type UploadedFile struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+}
+
+// uploadDir is where the session's uploaded files are staged.
+func (b *Browser) uploadDir(sessionID string) string {
+	name := sessionID
+	if name == "" {
+		name = "default"
+	}
+	return filepath.Join(b.cfg.UserDataDir, uploadsDirName, name)
+}
+
+// tabUploadDir is where the files set on a tab are staged. The page reads
+// them lazily, so they are kept until the tab closes.
+func (b *Browser) tabUploadDir(t *tab) string {
+	return filepath.Join(b.uploadDir(t.session), string(t.id))
+}
+
+// dropUploads removes the files staged for a closed tab.
+func (b *Browser) dropUploads(t *tab) {
+	if err := os.RemoveAll(b.tabUploadDir(t)); err != nil {
+		b.publish("agent", fmt.Sprintf("target %s: remove uploads: %v", t.id, err))
+	}
+}
+
+// uploadName reduces a client-supplied file name to a safe base name.
+func uploadName(name string) (string, error) {
+	name = filepath.Base(strings.ReplaceAll(strings.TrimSpace(name), "\\", "/"))
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return "", errors.New("browser: every file needs a name")
+	}
+	return name, nil
+}
+
+// Upload stages files in the tab's scratch directory and sets them on the
+// file input matched by selector, replacing its current selection.
+func (b *Browser) Upload(targetID string, timeout time.Duration, selector string, files []UploadFile) ([]UploadedFile, error) {
+	if selector == "" {
+		return nil, errors.New("browser: selector required")
+	}
+	if len(files) == 0 {
+		return nil, errors.New("browser: at least one file is required")
+	}
+	t, err := b.tab(targetID)
+	if err != nil {
+		return nil, err
+	}
+	root := b.tabUploadDir(t)
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("browser: create upload dir: %w", err)
+	}
+	// Each upload gets its own directory so files keep their names.
+	dir, err := os.MkdirTemp(root, "upload-")
+	if err != nil {
+		return nil, fmt.Errorf("browser: create upload dir: %w", err)
+	}
+
+	paths := make([]string, 0, len(files))
+	uploaded := make([]UploadedFile, 0, len(files))
+	for _, file := range files {
+		name, err := uploadName(file.Name)
+		if err == nil {
+			var size int64
+			size, err = stageUpload(filepath.Join(dir, name), file.Open)
+			uploaded = append(uploaded, UploadedFile{Name: name, Size: size})
+			paths = append(paths, filepath.Join(dir, name))
+		}
+		if err != nil {
+			_ = os.RemoveAll(dir)
+			return nil, err
+		}
+	}
+
+	err = b.run(targetID, timeout, "upload", fmt.Sprintf("Uploading %d file(s) into %s", len(paths), selector), func(ctx context.Context) (string, error) {
+		// File inputs are often hidden behind a styled control, so only
+		// wait for the element to exist.
+		if err := chromedp.Run(ctx,
+			chromedp.WaitReady(selector, chromedp.ByQuery),
+			chromedp.SetUploadFiles(selector, paths, chromedp.ByQuery),
+		); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("set %d file(s)", len(paths)), nil
+	})
+	if err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	return uploaded, nil
+}
+
+// stageUpload copies one file's content to path, closing the content before
+// it returns.
+func stageUpload(path string, open func() (io.ReadCloser, error)) (int64, error) {
+	r, err := open()
+	if err != nil {
+		return 0, fmt.Errorf("browser: open upload: %w", err)
+	}
+	defer r.Close()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return 0, fmt.Errorf("browser: duplicate file name %q", filepath.Base(path))
+		}
+		return 0, fmt.Errorf("browser: stage upload: %w", err)
+	}
+	size, err := io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("browser: stage upload: %w", err)
+	}
+	return size, nil
+}
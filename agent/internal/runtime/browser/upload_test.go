@@ -0,0 +1,196 @@
+package browser
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/chromedp/cdproto/target"
+)
+
+func TestUploadName(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"report.pdf", "report.pdf", false},
+		{"  photo.jpg ", "photo.jpg", false},
+		{"dir/nested/file.txt", "file.txt", false},
+		{`C:\Users\me\notes.txt`, "notes.txt", false},
+		{"../../etc/passwd", "passwd", false},
+		{"/absolute/path.csv", "path.csv", false},
+		{"name with spaces.txt", "name with spaces.txt", false},
+		{"", "", true},
+		{"   ", "", true},
+		{".", "", true},
+		{"..", "", true},
+		{"/", "", true},
+		{"dir/..", "", true},
+		{`\`, "", true},
+	}
+	for _, tt := range tests {
+		got, err := uploadName(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("uploadName(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+type trackedContent struct {
+	io.Reader
+	closed bool
+}
+
+func (c *trackedContent) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestStageUpload(t *testing.T) {
+	dir := t.TempDir()
+	content := &trackedContent{Reader: strings.NewReader("hello")}
+	open := func() (io.ReadCloser, error) { return content, nil }
+
+	size, err := stageUpload(filepath.Join(dir, "a.txt"), open)
+	if err != nil || size != 5 {
+		t.Fatalf("stageUpload() = %d, %v; want 5, nil", size, err)
+	}
+	if !content.closed {
+		t.Error("content was not closed after staging")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(data) != "hello" {
+		t.Errorf("staged file holds %q", data)
+	}
+
+	content = &trackedContent{Reader: strings.NewReader("again")}
+	if _, err := stageUpload(filepath.Join(dir, "a.txt"), open); err == nil || !strings.Contains(err.Error(), `duplicate file name "a.txt"`) {
+		t.Errorf("staging a duplicate name = %v", err)
+	}
+	if !content.closed {
+		t.Error("content was not closed after a failed staging")
+	}
+
+	failing := func() (io.ReadCloser, error) { return nil, errors.New("gone") }
+	if _, err := stageUpload(filepath.Join(dir, "b.txt"), failing); err == nil || !strings.HasPrefix(err.Error(), "browser: open upload") {
+		t.Errorf("staging unopenable content = %v", err)
+	}
+}
+
+func TestUploadValidation(t *testing.T) {
+	b := &Browser{}
+	file := UploadFile{Name: "a.txt", Open: func() (io.ReadCloser, error) { return io.NopCloser(strings.NewReader("a")), nil }}
+	if _, err := b.Upload("", 0, "", []UploadFile{file}); err == nil || err.Error() != "browser: selector required" {
+		t.Errorf("Upload without selector = %v", err)
+	}
+	if _, err := b.Upload("", 0, "input", nil); err == nil || err.Error() != "browser: at least one file is required" {
+		t.Errorf("Upload without files = %v", err)
+	}
+}
+
+func TestHandleUploadRejectsOtherFileFields(t *testing.T) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	_ = form.WriteField("selector", "input[type=file]")
+	part, _ := form.CreateFormFile("attachment", "a.txt")
+	_, _ = part.Write([]byte("a"))
+	_ = form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/dom/upload", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	(&Runtime{}).handleUpload(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `unexpected file field \"attachment\"`) {
+		t.Fatalf("handleUpload = %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestHandleUploadRejectsLargeBodies(t *testing.T) {
+	var multipartBody bytes.Buffer
+	form := multipart.NewWriter(&multipartBody)
+	_ = form.WriteField("selector", "input[type=file]")
+	part, _ := form.CreateFormFile(uploadField, "a.txt")
+	_, _ = part.Write(bytes.Repeat([]byte("a"), 4096))
+	_ = form.Close()
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{"multipart", form.FormDataContentType(), multipartBody.String()},
+		{"json", "application/json", `{"selector":"input","files":[{"name":"a.txt","content_base64":"` + strings.Repeat("QUJD", 1024) + `"}]}`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/v1/dom/upload", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		rec := httptest.NewRecorder()
+		(&Runtime{maxUpload: 1024}).handleUpload(rec, req)
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("%s: handleUpload = %d %s, want 413", tt.name, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestDropUploads(t *testing.T) {
+	b := &Browser{cfg: BrowserConfig{UserDataDir: t.TempDir()}, log: newLogEmitter()}
+	defer b.log.Close()
+	closed := &tab{id: "t1"}
+	open := &tab{id: "t2"}
+	for _, tb := range []*tab{closed, open} {
+		dir := filepath.Join(b.tabUploadDir(tb), "upload-1")
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b.dropUploads(closed)
+	if _, err := os.Stat(b.tabUploadDir(closed)); !os.IsNotExist(err) {
+		t.Errorf("uploads of the closed tab remain: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(b.tabUploadDir(open), "upload-1", "a.txt")); err != nil {
+		t.Errorf("uploads of the open tab were removed: %v", err)
+	}
+}
+
+func TestCloseRemovesUploads(t *testing.T) {
+	dir := t.TempDir()
+	b := &Browser{
+		cfg:            BrowserConfig{UserDataDir: dir},
+		log:            newLogEmitter(),
+		cancelLifetime: func() {},
+		userDataDir:    dir,
+		tabs:           make(map[target.ID]*tab),
+		active:         make(map[string]target.ID),
+		sessions:       make(map[string]*session),
+		policies:       make(map[string]*sessionPolicy),
+	}
+	kept := filepath.Join(b.downloadDir(""), "report.pdf")
+	staged := filepath.Join(b.uploadDir("s1"), "t1", "upload-1", "a.txt")
+	for _, file := range []string{kept, staged} {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte("x"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b.Close()
+	if _, err := os.Stat(filepath.Join(dir, uploadsDirName)); !os.IsNotExist(err) {
+		t.Errorf("uploads remain after Close: %v", err)
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("downloads were removed with a kept profile: %v", err)
+	}
+}
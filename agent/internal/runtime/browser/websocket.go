@@ -0,0 +1,351 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/chromedp"
+)
+
+const (
+	defaultWSMaxFrames       = 1000
+	defaultWSMaxPayloadBytes = 64 << 10
+)
+
+// Directions of a captured WebSocket frame.
+const (
+	FrameSent     = "sent"
+	FrameReceived = "received"
+	FrameError    = "error"
+	FrameClosed   = "closed"
+)
+
+// ErrWebSocketsNotStarted is returned when reading frames from a target that
+// has never been recorded.
+var ErrWebSocketsNotStarted = errors.New("browser: websocket capture not started")
+
+// WebSocketOptions configure a frame capture. URLPatterns are globs as used
+// by route rules; an empty list captures every socket. The oldest frames are
+// dropped once MaxFrames are held.
+type WebSocketOptions struct {
+	URLPatterns     []string
+	MaxFrames       int
+	MaxPayloadBytes int
+}
+
+// WebSocketFrame is one captured frame, or a socket error or close. Binary
+// payloads (opcode 2) are base64 encoded as Chrome reports them. On a
+// streamed frame, Dropped counts the frames the stream skipped just before
+// it because its reader fell behind; they can be read back from the capture.
+type WebSocketFrame struct {
+	Seq       int64     `json:"seq"`
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+	URL       string    `json:"url"`
+	Direction string    `json:"direction"`
+	Opcode    int       `json:"opcode,omitempty"`
+	Data      string    `json:"data,omitempty"`
+	Base64    bool      `json:"base64,omitempty"`
+	Truncated bool      `json:"truncated,omitempty"`
+	Dropped   int       `json:"dropped,omitempty"`
+}
+
+// WebSocketStatus describes a target's frame capture.
+type WebSocketStatus struct {
+	TargetID    string     `json:"target_id"`
+	Recording   bool       `json:"recording"`
+	URLPatterns []string   `json:"url_patterns"`
+	Frames      int        `json:"frames"`
+	Dropped     int        `json:"dropped"`
+	StartedAt   time.Time  `json:"started_at"`
+	StoppedAt   *time.Time `json:"stopped_at,omitempty"`
+}
+
+type wsRecorder struct {
+	mu        sync.Mutex
+	cancel    context.CancelFunc
+	opts      WebSocketOptions
+	patterns  []*regexp.Regexp
+	started   time.Time
+	stopped   time.Time
+	recording bool
+	urls      map[network.RequestID]string
+	frames    []WebSocketFrame
+	seq       int64
+	dropped   int
+	subs      map[int64]*wsSubscriber
+	nextSub   int64
+}
+
+// wsSubscriber is an open stream of a capture and the number of frames it
+// missed since its last delivery.
+type wsSubscriber struct {
+	ch      chan WebSocketFrame
+	dropped int
+}
+
+// StartWebSockets begins capturing the target's WebSocket frames, discarding
+// any previous capture.
+func (b *Browser) StartWebSockets(targetID string, timeout time.Duration, opts WebSocketOptions) (WebSocketStatus, error) {
+	if opts.MaxFrames <= 0 {
+		opts.MaxFrames = defaultWSMaxFrames
+	}
+	if opts.MaxPayloadBytes <= 0 {
+		opts.MaxPayloadBytes = defaultWSMaxPayloadBytes
+	}
+	var patterns []*regexp.Regexp
+	var globs []string
+	for _, glob := range opts.URLPatterns {
+		if glob = strings.TrimSpace(glob); glob == "" {
+			continue
+		}
+		pattern, err := globPattern(glob)
+		if err != nil {
+			return WebSocketStatus{}, fmt.Errorf("browser: invalid url pattern %q: %w", glob, err)
+		}
+		patterns = append(patterns, pattern)
+		globs = append(globs, glob)
+	}
+	opts.URLPatterns = globs
+
+	t, err := b.tab(targetID)
+	if err != nil {
+		return WebSocketStatus{}, err
+	}
+	err = b.run(targetID, timeout, "websockets_start", fmt.Sprintf("Capturing WebSocket frames (%d patterns)", len(globs)), func(ctx context.Context) (string, error) {
+		lctx, cancel := context.WithCancel(t.ctx)
+		rec := &wsRecorder{
+			cancel:    cancel,
+			opts:      opts,
+			patterns:  patterns,
+			started:   time.Now().UTC(),
+			recording: true,
+			urls:      make(map[network.RequestID]string),
+			subs:      make(map[int64]*wsSubscriber),
+		}
+		b.tabsMu.Lock()
+		previous := t.ws
+		t.ws = rec
+		b.tabsMu.Unlock()
+		if previous != nil {
+			previous.stop()
+		}
+
+		chromedp.ListenTarget(lctx, rec.record)
+		// Closing the tab ends the capture and any open streams.
+		go func() {
+			<-lctx.Done()
+			rec.stop()
+		}()
+		return "", nil
+	})
+	if err != nil {
+		return WebSocketStatus{}, err
+	}
+	return b.WebSocketStatus(targetID)
+}
+
+// StopWebSockets stops capturing and ends any open streams; captured frames
+// stay available until the next StartWebSockets.
+func (b *Browser) StopWebSockets(targetID string) (WebSocketStatus, error) {
+	rec, t, err := b.wsRecorder(targetID)
+	if err != nil {
+		return WebSocketStatus{}, err
+	}
+	rec.stop()
+	b.publish("agent", fmt.Sprintf("WebSocket capture stopped on %s", t.id))
+	return rec.status(string(t.id)), nil
+}
+
+// WebSocketStatus reports the state of the target's capture.
+func (b *Browser) WebSocketStatus(targetID string) (WebSocketStatus, error) {
+	rec, t, err := b.wsRecorder(targetID)
+	if err != nil {
+		return WebSocketStatus{}, err
+	}
+	return rec.status(string(t.id)), nil
+}
+
+// WebSocketFrames returns the captured frames with a sequence number greater
+// than since.
+func (b *Browser) WebSocketFrames(targetID string, since int64) ([]WebSocketFrame, error) {
+	rec, _, err := b.wsRecorder(targetID)
+	if err != nil {
+		return nil, err
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	frames := make([]WebSocketFrame, 0, len(rec.frames))
+	for _, frame := range rec.frames {
+		if frame.Seq > since {
+			frames = append(frames, frame)
+		}
+	}
+	return frames, nil
+}
+
+// SubscribeWebSockets streams frames as they are captured. Frames that do not
+// fit in the buffer are skipped and counted on the next delivered frame. The
+// channel is closed when the capture stops; the returned callback must be
+// invoked to release resources.
+func (b *Browser) SubscribeWebSockets(targetID string, buffer int) (<-chan WebSocketFrame, func(), error) {
+	rec, _, err := b.wsRecorder(targetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if buffer <= 0 {
+		buffer = 32
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	ch := make(chan WebSocketFrame, buffer)
+	if !rec.recording {
+		close(ch)
+		return ch, func() {}, nil
+	}
+	id := rec.nextSub
+	rec.nextSub++
+	rec.subs[id] = &wsSubscriber{ch: ch}
+	return ch, func() {
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		if sub, ok := rec.subs[id]; ok {
+			delete(rec.subs, id)
+			close(sub.ch)
+		}
+	}, nil
+}
+
+func (b *Browser) wsRecorder(targetID string) (*wsRecorder, *tab, error) {
+	t, err := b.tab(targetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	b.tabsMu.RLock()
+	rec := t.ws
+	b.tabsMu.RUnlock()
+	if rec == nil {
+		return nil, nil, ErrWebSocketsNotStarted
+	}
+	return rec, t, nil
+}
+
+func (rec *wsRecorder) record(ev any) {
+	switch e := ev.(type) {
+	case *network.EventWebSocketCreated:
+		rec.mu.Lock()
+		rec.urls[e.RequestID] = e.URL
+		rec.mu.Unlock()
+	case *network.EventWebSocketFrameSent:
+		rec.add(e.RequestID, FrameSent, e.Response)
+	case *network.EventWebSocketFrameReceived:
+		rec.add(e.RequestID, FrameReceived, e.Response)
+	case *network.EventWebSocketFrameError:
+		rec.add(e.RequestID, FrameError, &network.WebSocketFrame{PayloadData: e.ErrorMessage})
+	case *network.EventWebSocketClosed:
+		rec.add(e.RequestID, FrameClosed, nil)
+	}
+}
+
+func (rec *wsRecorder) add(id network.RequestID, direction string, payload *network.WebSocketFrame) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if !rec.recording {
+		return
+	}
+	url, known := rec.urls[id]
+	if direction == FrameClosed {
+		delete(rec.urls, id)
+	}
+	if len(rec.patterns) > 0 {
+		// Sockets opened before the capture started have no known URL.
+		if !known || !matchesAny(rec.patterns, url) {
+			return
+		}
+	}
+
+	rec.seq++
+	frame := WebSocketFrame{
+		Seq:       rec.seq,
+		Time:      time.Now().UTC(),
+		RequestID: string(id),
+		URL:       url,
+		Direction: direction,
+	}
+	if payload != nil {
+		frame.Opcode = int(payload.Opcode)
+		frame.Data = payload.PayloadData
+		frame.Base64 = payload.Opcode == 2
+		if len(frame.Data) > rec.opts.MaxPayloadBytes {
+			cut := rec.opts.MaxPayloadBytes
+			if frame.Base64 {
+				cut -= cut % 4
+			}
+			frame.Data = frame.Data[:cut]
+			frame.Truncated = true
+		}
+	}
+	if len(rec.frames) >= rec.opts.MaxFrames {
+		rec.frames = rec.frames[1:]
+		rec.dropped++
+	}
+	rec.frames = append(rec.frames, frame)
+	for _, sub := range rec.subs {
+		streamed := frame
+		streamed.Dropped = sub.dropped
+		select {
+		case sub.ch <- streamed:
+			sub.dropped = 0
+		default:
+			sub.dropped++
+		}
+	}
+}
+
+func matchesAny(patterns []*regexp.Regexp, value string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (rec *wsRecorder) stop() {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if !rec.recording {
+		return
+	}
+	rec.recording = false
+	rec.stopped = time.Now().UTC()
+	rec.cancel()
+	for id, sub := range rec.subs {
+		delete(rec.subs, id)
+		close(sub.ch)
+	}
+}
+
+func (rec *wsRecorder) status(targetID string) WebSocketStatus {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	status := WebSocketStatus{
+		TargetID:    targetID,
+		Recording:   rec.recording,
+		URLPatterns: append([]string{}, rec.opts.URLPatterns...),
+		Frames:      len(rec.frames),
+		Dropped:     rec.dropped,
+		StartedAt:   rec.started,
+	}
+	if !rec.stopped.IsZero() {
+		stopped := rec.stopped
+		status.StoppedAt = &stopped
+	}
+	return status
+}
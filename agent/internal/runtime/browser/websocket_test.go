@@ -0,0 +1,212 @@
+package browser
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/chromedp/cdproto/network"
+	"github.com/chromedp/cdproto/target"
+)
+
+func newTestWSRecorder(opts WebSocketOptions, globs ...string) *wsRecorder {
+	rec := &wsRecorder{
+		cancel:    func() {},
+		opts:      opts,
+		recording: true,
+		urls:      make(map[network.RequestID]string),
+		subs:      make(map[int64]*wsSubscriber),
+	}
+	for _, glob := range globs {
+		pattern, _ := globPattern(glob)
+		rec.patterns = append(rec.patterns, pattern)
+		rec.opts.URLPatterns = append(rec.opts.URLPatterns, glob)
+	}
+	if rec.opts.MaxFrames == 0 {
+		rec.opts.MaxFrames = defaultWSMaxFrames
+	}
+	if rec.opts.MaxPayloadBytes == 0 {
+		rec.opts.MaxPayloadBytes = defaultWSMaxPayloadBytes
+	}
+	return rec
+}
+
+func text(data string) *network.WebSocketFrame {
+	return &network.WebSocketFrame{Opcode: 1, PayloadData: data}
+}
+
+func frameSummary(frames []WebSocketFrame) []string {
+	var out []string
+	for _, f := range frames {
+		out = append(out, f.RequestID+" "+f.Direction+" "+f.Data)
+	}
+	return out
+}
+
+func TestWSRecorderFiltersByURL(t *testing.T) {
+	rec := newTestWSRecorder(WebSocketOptions{}, "wss://chat.example.com/*")
+	for _, ev := range []any{
+		&network.EventWebSocketCreated{RequestID: "chat", URL: "wss://chat.example.com/live"},
+		&network.EventWebSocketCreated{RequestID: "ads", URL: "wss://ads.example.com/feed"},
+		&network.EventWebSocketFrameSent{RequestID: "chat", Response: text("hi")},
+		&network.EventWebSocketFrameReceived{RequestID: "ads", Response: text("buy")},
+		// The socket was open before the capture started, so its URL is
+		// unknown and it cannot match.
+		&network.EventWebSocketFrameReceived{RequestID: "old", Response: text("stale")},
+		&network.EventWebSocketFrameError{RequestID: "chat", ErrorMessage: "bad frame"},
+		&network.EventWebSocketClosed{RequestID: "chat"},
+		// A closed socket is forgotten.
+		&network.EventWebSocketFrameReceived{RequestID: "chat", Response: text("late")},
+	} {
+		rec.record(ev)
+	}
+	want := []string{"chat sent hi", "chat error bad frame", "chat closed "}
+	if got := frameSummary(rec.frames); !reflect.DeepEqual(got, want) {
+		t.Fatalf("frames = %q, want %q", got, want)
+	}
+	for i, f := range rec.frames {
+		if f.Seq != int64(i+1) || f.URL != "wss://chat.example.com/live" {
+			t.Errorf("frame %d = seq %d url %q", i, f.Seq, f.URL)
+		}
+	}
+
+	// Without patterns every socket is captured, known or not.
+	rec = newTestWSRecorder(WebSocketOptions{})
+	rec.record(&network.EventWebSocketFrameReceived{RequestID: "old", Response: text("stale")})
+	if len(rec.frames) != 1 || rec.frames[0].URL != "" {
+		t.Fatalf("frames without patterns = %+v", rec.frames)
+	}
+}
+
+func TestWSRecorderEvictsOldestFrames(t *testing.T) {
+	rec := newTestWSRecorder(WebSocketOptions{MaxFrames: 3})
+	for _, data := range []string{"1", "2", "3", "4", "5"} {
+		rec.add("s", FrameReceived, text(data))
+	}
+	var seqs []int64
+	for _, f := range rec.frames {
+		seqs = append(seqs, f.Seq)
+	}
+	if !reflect.DeepEqual(seqs, []int64{3, 4, 5}) {
+		t.Fatalf("kept frames %v, want 3 4 5", seqs)
+	}
+	if status := rec.status("tab1"); status.Frames != 3 || status.Dropped != 2 {
+		t.Fatalf("status = %+v, want 3 frames and 2 dropped", status)
+	}
+}
+
+func TestWSRecorderTruncatesPayloads(t *testing.T) {
+	rec := newTestWSRecorder(WebSocketOptions{MaxPayloadBytes: 10})
+	rec.add("s", FrameSent, text("0123456789"))
+	rec.add("s", FrameSent, text("0123456789abc"))
+	// Binary payloads are cut on a base64 quantum so they still decode.
+	rec.add("s", FrameReceived, &network.WebSocketFrame{Opcode: 2, PayloadData: "QUJDREVGR0hJSktM"})
+
+	tests := []struct {
+		data      string
+		base64    bool
+		truncated bool
+	}{
+		{"0123456789", false, false},
+		{"0123456789", false, true},
+		{"QUJDREVG", true, true},
+	}
+	for i, tt := range tests {
+		f := rec.frames[i]
+		if f.Data != tt.data || f.Base64 != tt.base64 || f.Truncated != tt.truncated {
+			t.Errorf("frame %d = %q base64 %v truncated %v, want %q %v %v", i, f.Data, f.Base64, f.Truncated, tt.data, tt.base64, tt.truncated)
+		}
+	}
+}
+
+func newWSBrowser(rec *wsRecorder) *Browser {
+	return &Browser{
+		log:    newLogEmitter(),
+		ctx:    context.Background(),
+		tabs:   map[target.ID]*tab{"tab1": {id: "tab1", ctx: context.Background(), ws: rec}},
+		active: map[string]target.ID{"": "tab1"},
+	}
+}
+
+func TestWebSocketSubscribers(t *testing.T) {
+	rec := newTestWSRecorder(WebSocketOptions{})
+	b := newWSBrowser(rec)
+	defer b.log.Close()
+
+	slow, unsubscribeSlow, err := b.SubscribeWebSockets("", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fast, unsubscribeFast, err := b.SubscribeWebSockets("", 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range []string{"1", "2", "3"} {
+		rec.add("s", FrameReceived, text(data))
+	}
+	// The slow stream holds one frame and misses the next two; the count
+	// arrives with the next frame it has room for.
+	if f := <-slow; f.Seq != 1 || f.Dropped != 0 {
+		t.Fatalf("slow stream got seq %d dropped %d, want 1 and 0", f.Seq, f.Dropped)
+	}
+	rec.add("s", FrameReceived, text("4"))
+	if f := <-slow; f.Seq != 4 || f.Dropped != 2 {
+		t.Fatalf("slow stream got seq %d dropped %d, want 4 and 2", f.Seq, f.Dropped)
+	}
+	for want := int64(1); want <= 4; want++ {
+		if f := <-fast; f.Seq != want || f.Dropped != 0 {
+			t.Fatalf("fast stream got seq %d dropped %d, want %d and 0", f.Seq, f.Dropped, want)
+		}
+	}
+	// The capture itself keeps every frame without a drop count.
+	frames, err := b.WebSocketFrames("", 0)
+	if err != nil || len(frames) != 4 || frames[3].Dropped != 0 {
+		t.Fatalf("WebSocketFrames() = %+v, %v", frames, err)
+	}
+
+	// Unsubscribing closes the stream once and leaves the others open.
+	unsubscribeSlow()
+	unsubscribeSlow()
+	if _, ok := <-slow; ok {
+		t.Fatal("slow stream still open after unsubscribe")
+	}
+	rec.add("s", FrameReceived, text("5"))
+	if f := <-fast; f.Seq != 5 {
+		t.Fatalf("fast stream got seq %d after unsubscribe, want 5", f.Seq)
+	}
+
+	// Stopping closes the remaining streams and ignores later frames.
+	stopped, err := b.StopWebSockets("")
+	if err != nil || stopped.Recording || stopped.StoppedAt == nil {
+		t.Fatalf("StopWebSockets() = %+v, %v", stopped, err)
+	}
+	if _, ok := <-fast; ok {
+		t.Fatal("fast stream still open after stop")
+	}
+	unsubscribeFast()
+	rec.add("s", FrameReceived, text("6"))
+	if status := rec.status("tab1"); status.Frames != 5 {
+		t.Fatalf("frames after stop = %d, want 5", status.Frames)
+	}
+
+	late, unsubscribeLate, err := b.SubscribeWebSockets("", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unsubscribeLate()
+	if _, ok := <-late; ok {
+		t.Fatal("subscription to a stopped capture is open")
+	}
+}
+
+func TestWebSocketsNotStarted(t *testing.T) {
+	b := newWSBrowser(nil)
+	defer b.log.Close()
+	if _, _, err := b.SubscribeWebSockets("", 1); !errors.Is(err, ErrWebSocketsNotStarted) {
+		t.Fatalf("SubscribeWebSockets() error = %v", err)
+	}
+	if _, err := b.WebSocketFrames("", 0); !errors.Is(err, ErrWebSocketsNotStarted) {
+		t.Fatalf("WebSocketFrames() error = %v", err)
+	}
+}